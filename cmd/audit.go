@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/spiarh/gojo/pkg/audit"
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var defaultAuditRepos = []string{"main", "community"}
+
+func Audit() (*cobra.Command, error) {
+	var command = &cobra.Command{
+		Use:   "audit",
+		Short: "Report known vulnerabilities of the Alpine packages of an image",
+		Example: `gojo audit --image haproxy
+gojo audit --image haproxy --installed ./installed --fail-on high
+gojo audit --image haproxy --secdb-file ./main.json --secdb-file ./community.json`,
+		RunE:              func(cmd *cobra.Command, args []string) error { return auditImage(cmd, args) },
+		SilenceUsage:      true,
+		PersistentPreRunE: SetGlobalLogLevel,
+	}
+
+	if err := AddCommonPersistentFlags(command, true); err != nil {
+		return nil, err
+	}
+
+	command.PersistentFlags().StringSlice(core.SecDBFileFlag, nil, "Local secdb file to use instead of downloading it, can be repeated")
+	command.PersistentFlags().String(core.SecDBMirrorFlag, audit.SecDBDefaultMirror, "Mirror serving the secdb files")
+	command.PersistentFlags().String(core.CacheDirFlag, audit.DefaultSecDBCacheDir(), "Directory where the downloaded secdb files are cached")
+	command.PersistentFlags().String(core.InstalledFlag, "", "apk installed database of the image, e.g extracted from /lib/apk/db/installed")
+	command.PersistentFlags().String(core.SeverityFileFlag, "", "JSON file mapping CVE IDs to a severity {low,medium,high,critical}")
+	command.PersistentFlags().String(core.FailOnFlag, "", "Exit with an error if an unfixed CVE has at least this severity {low,medium,high,critical}")
+	command.PersistentFlags().StringP(core.VersionIDFlag, "v", "", "Alpine version ID used for the installed packages when no Alpine source is defined")
+	command.PersistentFlags().StringSliceP(core.RepoFlag, "r", defaultAuditRepos, "Alpine repositories used for the installed packages")
+
+	return command, nil
+}
+
+type auditOptions struct {
+	secdbFiles   []string
+	mirror       string
+	cacheDir     string
+	installed    string
+	severityFile string
+	failOn       audit.Severity
+	versionId    string
+	repos        []string
+}
+
+func getAuditOptions(flagSet *pflag.FlagSet) (auditOptions, error) {
+	var opt auditOptions
+	var err error
+
+	if opt.secdbFiles, err = flagSet.GetStringSlice(core.SecDBFileFlag); err != nil {
+		return opt, err
+	}
+	if opt.mirror, err = flagSet.GetString(core.SecDBMirrorFlag); err != nil {
+		return opt, err
+	}
+	if opt.cacheDir, err = flagSet.GetString(core.CacheDirFlag); err != nil {
+		return opt, err
+	}
+	if opt.installed, err = flagSet.GetString(core.InstalledFlag); err != nil {
+		return opt, err
+	}
+	if opt.severityFile, err = flagSet.GetString(core.SeverityFileFlag); err != nil {
+		return opt, err
+	}
+	failOn, err := flagSet.GetString(core.FailOnFlag)
+	if err != nil {
+		return opt, err
+	}
+	if failOn != "" {
+		if opt.failOn, err = audit.ParseSeverity(failOn); err != nil {
+			return opt, err
+		}
+	}
+	if opt.versionId, err = flagSet.GetString(core.VersionIDFlag); err != nil {
+		return opt, err
+	}
+	if opt.repos, err = flagSet.GetStringSlice(core.RepoFlag); err != nil {
+		return opt, err
+	}
+
+	return opt, nil
+}
+
+func auditImage(command *cobra.Command, args []string) error {
+	flagSet := command.Flags()
+	opt, err := getOptions(flagSet)
+	if err != nil {
+		return err
+	}
+	auditOpt, err := getAuditOptions(flagSet)
+	if err != nil {
+		return err
+	}
+
+	build, err := core.NewBuildFromManifest(opt.buildFilePath)
+	if err != nil {
+		return err
+	}
+
+	pkgs, secdbRepos := getAlpineFactPackages(build)
+
+	if auditOpt.installed != "" {
+		data, err := ioutil.ReadFile(auditOpt.installed)
+		if err != nil {
+			return err
+		}
+		installed, err := audit.ParseInstalled(data)
+		if err != nil {
+			return err
+		}
+		pkgs = append(pkgs, installed...)
+
+		if auditOpt.versionId != "" {
+			for _, repo := range auditOpt.repos {
+				secdbRepos[secdbRepo{versionId: auditOpt.versionId, repo: repo}] = struct{}{}
+			}
+		}
+	}
+
+	if len(pkgs) == 0 {
+		log.Warn().Msg("no Alpine package to audit")
+		return nil
+	}
+
+	secdbs, err := loadSecDBs(auditOpt, secdbRepos)
+	if err != nil {
+		return err
+	}
+
+	var severities audit.Severities
+	if auditOpt.severityFile != "" {
+		if severities, err = audit.NewSeveritiesFromFile(auditOpt.severityFile); err != nil {
+			return err
+		}
+	}
+
+	findings := audit.Audit(secdbs, pkgs, severities)
+	if err := printFindings(findings); err != nil {
+		return err
+	}
+
+	unfixed := audit.Unfixed(findings, audit.SeverityLow)
+	log.Info().Int("findings", len(findings)).
+		Int("unfixed", len(unfixed)).
+		Msg("audit done")
+
+	if auditOpt.failOn == "" {
+		return nil
+	}
+	if failed := audit.Unfixed(findings, auditOpt.failOn); len(failed) > 0 {
+		return fmt.Errorf("%d unfixed CVEs with severity %s or higher", len(failed), auditOpt.failOn)
+	}
+
+	return nil
+}
+
+type secdbRepo struct {
+	versionId string
+	repo      string
+}
+
+// getAlpineFactPackages returns the packages referenced by the Alpine facts
+// and the secdb repositories of their sources.
+func getAlpineFactPackages(build *core.Build) ([]audit.Package, map[secdbRepo]struct{}) {
+	var pkgs []audit.Package
+	repos := make(map[secdbRepo]struct{})
+
+	for _, src := range build.Spec.Sources {
+		if src.Alpine == nil {
+			continue
+		}
+		for _, repo := range src.Alpine.GetRepositories() {
+			repos[secdbRepo{versionId: src.Alpine.VersionId, repo: repo}] = struct{}{}
+		}
+
+		for _, fact := range build.Spec.Facts {
+			if fact.Source != src.Name || fact.Kind != core.VersionFactKind {
+				continue
+			}
+			if fact.Value == "" {
+				log.Warn().Str(core.NameKey, fact.Name).Msg("fact has no value, skip")
+				continue
+			}
+			pkgs = append(pkgs, audit.Package{
+				Name:    src.Alpine.Package,
+				Version: fact.Value,
+				From:    fact.Name,
+			})
+		}
+	}
+
+	return pkgs, repos
+}
+
+func loadSecDBs(opt auditOptions, repos map[secdbRepo]struct{}) ([]*audit.SecDB, error) {
+	var secdbs []*audit.SecDB
+
+	// Local files are used as is, offline.
+	if len(opt.secdbFiles) > 0 {
+		for _, f := range opt.secdbFiles {
+			secdb, err := audit.NewSecDBFromFile(f)
+			if err != nil {
+				return nil, err
+			}
+			secdbs = append(secdbs, secdb)
+		}
+		return secdbs, nil
+	}
+
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no secdb to load, use --%s or --%s", core.SecDBFileFlag, core.VersionIDFlag)
+	}
+
+	loader := &audit.SecDBLoader{
+		Mirror:   opt.mirror,
+		CacheDir: opt.cacheDir,
+		TTL:      audit.SecDBDefaultTTL,
+	}
+	for r := range repos {
+		secdb, err := loader.Load(r.versionId, r.repo)
+		if err != nil {
+			return nil, err
+		}
+		secdbs = append(secdbs, secdb)
+	}
+
+	return secdbs, nil
+}
+
+func printFindings(findings []audit.Finding) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PACKAGE\tVERSION\tFROM\tCVE\tFIXED-IN\tSTATUS\tSEVERITY")
+	for _, f := range findings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			f.Package.Name, f.Package.Version, f.Package.From,
+			f.CVE, f.FixedIn, f.Status, f.Severity)
+	}
+	return w.Flush()
+}
@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
 	"github.com/spiarh/gojo/pkg/core"
 	"github.com/spiarh/gojo/pkg/manager"
+	"github.com/spiarh/gojo/pkg/metrics"
 )
 
 func Build() (*cobra.Command, error) {
@@ -20,32 +23,36 @@ func Build() (*cobra.Command, error) {
 
 	// Buildah
 	command.AddCommand(buildahCommand)
-	if err := AddCommonPersistentFlags(buildahCommand); err != nil {
+	if err := AddCommonPersistentFlags(buildahCommand, true); err != nil {
 		return nil, err
 	}
 	AddCommonBuildFlags(buildahCommand)
+	AddMetricsFlags(buildahCommand)
 
 	// Buildkit
 	command.AddCommand(buildkitCommand)
-	if err := AddCommonPersistentFlags(buildkitCommand); err != nil {
+	if err := AddCommonPersistentFlags(buildkitCommand, true); err != nil {
 		return nil, err
 	}
 	AddCommonBuildFlags(buildkitCommand)
+	AddMetricsFlags(buildkitCommand)
 	AddBuildkitFlags(buildkitCommand)
 
 	// Podman
 	command.AddCommand(podmanCommand)
-	if err := AddCommonPersistentFlags(podmanCommand); err != nil {
+	if err := AddCommonPersistentFlags(podmanCommand, true); err != nil {
 		return nil, err
 	}
 	AddCommonBuildFlags(podmanCommand)
+	AddMetricsFlags(podmanCommand)
 
 	// Podman
 	command.AddCommand(kanikoCommand)
-	if err := AddCommonPersistentFlags(kanikoCommand); err != nil {
+	if err := AddCommonPersistentFlags(kanikoCommand, true); err != nil {
 		return nil, err
 	}
 	AddCommonBuildFlags(kanikoCommand)
+	AddMetricsFlags(kanikoCommand)
 
 	return command, nil
 }
@@ -82,6 +89,7 @@ func build(command *cobra.Command, args []string) error {
 		return err
 	}
 	log.Info().Bool(core.EnabledKey, opt.dryRun).Msg(core.DryRunFlag)
+	defer writeMetricsFile(flagSet)
 
 	build, err := core.NewBuildFromManifest(opt.buildFilePath)
 	if err != nil {
@@ -99,10 +107,24 @@ func build(command *cobra.Command, args []string) error {
 		return err
 	}
 
-	err = mgr.Build(build)
-	if err != nil {
+	return buildImage(mgr, mgrType, opt, build)
+}
+
+// buildImage builds the image and records the metrics of the build, the
+// dry runs are not recorded.
+func buildImage(mgr manager.Manager, mgrType string, opt CommonOptions, build *core.Build) error {
+	start := time.Now()
+	err := mgr.Build(build)
+	if opt.dryRun {
 		return err
 	}
 
+	metrics.BuildDuration.Observe(time.Since(start).Seconds(), mgrType)
+	if err != nil {
+		metrics.Builds.Inc(mgrType, metrics.StatusFailure)
+		return err
+	}
+	metrics.Builds.Inc(mgrType, metrics.StatusSuccess)
+	metrics.ImageLastSuccess.Set(float64(time.Now().Unix()), opt.imageName)
 	return nil
 }
@@ -7,19 +7,22 @@ import (
 	"path/filepath"
 
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/spiarh/gojo/pkg/core"
 	"github.com/spiarh/gojo/pkg/manager"
+	"github.com/spiarh/gojo/pkg/metrics"
 )
 
 const (
 	imagesDirEnv = "GOJO_IMAGES_DIR"
 )
 
-// AddCommonPersistentFlags adds some common flags to a cobra command.
-func AddCommonPersistentFlags(command *cobra.Command) error {
+// AddCommonPersistentFlags adds some common flags to a cobra command, the
+// commands running on all the images don't require --image.
+func AddCommonPersistentFlags(command *cobra.Command, imageRequired bool) error {
 	command.PersistentFlags().Bool(core.DryRunFlag, false, "Do not write files nor execute commands")
 	command.PersistentFlags().StringP(core.ContainerfileFlag, "c", core.ContainerfileName, "Name of the Containerfile")
 	command.PersistentFlags().StringP(core.BuildfileFlag, "f", core.BuildFileName, "Name of the buildfile")
@@ -27,6 +30,9 @@ func AddCommonPersistentFlags(command *cobra.Command) error {
 	command.PersistentFlags().StringP(core.ImagesDirFlag, "d", os.Getenv(imagesDirEnv), "Path to the container images directory")
 	command.PersistentFlags().StringP(core.LogLevelFlag, "l", core.DefaultLogLevel, "Log level {debug,info,warn,error}")
 
+	if !imageRequired {
+		return nil
+	}
 	if err := command.MarkPersistentFlagRequired(core.ImageFlag); err != nil {
 		return err
 	}
@@ -39,6 +45,11 @@ func AddCommonBuildFlags(command *cobra.Command) {
 	command.PersistentFlags().Bool(core.TagLatestFlag, false, "Tag the built image as latest")
 }
 
+// AddMetricsFlags adds the metrics flags to a cobra command.
+func AddMetricsFlags(command *cobra.Command) {
+	command.PersistentFlags().String(core.MetricsFileFlag, "", "Write the metrics to a file of the textfile collector of the node exporter, e.g gojo.prom")
+}
+
 // AddBuildkitFlags adds some buildkit flags to a cobra command.
 func AddBuildkitFlags(command *cobra.Command) {
 	command.PersistentFlags().String(core.AddrFlag, "", "Buildkitd address, e.g podman-container://buildkitd")
@@ -110,3 +121,33 @@ func getOptions(flagSet *pflag.FlagSet) (CommonOptions, error) {
 
 	return opt, nil
 }
+
+// withImage returns the options of another image of the images directory.
+func (o CommonOptions) withImage(imageName string) CommonOptions {
+	o.imageName = imageName
+	o.imageDir = path.Join(o.imagesDir, imageName)
+	o.buildFilePath = path.Join(o.imageDir, o.buildFileName)
+	o.containerFilePath = path.Join(o.imageDir, o.containerFileName)
+	return o
+}
+
+// writeMetricsFile writes the metrics to the file of --metrics-file, if
+// set, the command doesn't fail on errors.
+func writeMetricsFile(flagSet *pflag.FlagSet) {
+	path, err := flagSet.GetString(core.MetricsFileFlag)
+	if err != nil || path == "" {
+		return
+	}
+	if err := metrics.Default.WriteFile(path); err != nil {
+		log.Error().AnErr(core.ErrKey, err).Str(core.FileKey, path).Msg("write metrics file failed")
+	}
+}
+
+// setUpdateAvailable sets the update available gauge of the image.
+func setUpdateAvailable(imageName string, available bool) {
+	value := 0.0
+	if available {
+		value = 1
+	}
+	metrics.ImageUpdateAvailable.Set(value, imageName)
+}
@@ -2,16 +2,25 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/provider"
+	"github.com/spiarh/gojo/pkg/pullrequest"
 	"github.com/spiarh/gojo/pkg/util"
 )
 
@@ -26,21 +35,52 @@ func Commit() (*cobra.Command, error) {
 		PersistentPreRunE: SetGlobalLogLevel,
 	}
 
-	if err := AddCommonPersistentFlags(command); err != nil {
+	// The image is required unless --all is set.
+	if err := AddCommonPersistentFlags(command, false); err != nil {
 		return nil, err
 	}
 
+	command.PersistentFlags().Bool(core.AllFlag, false, "Commit the modified build files of all the images in a single commit")
+	command.PersistentFlags().Int(core.PushRetriesFlag, 3, "Number of rebases on the remote branch when the push is rejected in bulk mode")
+	if err := AddCommitFlags(command); err != nil {
+		return nil, err
+	}
+
+	return command, nil
+}
+
+// AddCommitFlags adds the flags of the commit of the build files to a
+// cobra command.
+func AddCommitFlags(command *cobra.Command) error {
 	command.PersistentFlags().StringP(core.NameFlag, "n", "", "Name of the git commit author")
 	command.PersistentFlags().StringP(core.EmailFlag, "e", "", "Email of the git commit author")
+	command.PersistentFlags().String(core.MessageTemplateFlag, "", "Path of the template of the commit message, the old and new build files and the changed facts are available")
+	command.PersistentFlags().StringSlice(core.ExtraFileFlag, nil, "Glob patterns of the files of the image directories committed with the build file, added to spec.commitFiles, e.g *.lock")
+	command.PersistentFlags().String(core.SignKeyFlag, "", "Private key file signing the commit and the tag")
+	command.PersistentFlags().String(core.SignFormatFlag, util.GitSignFormatOpenPGP, "Format of the signing key {openpgp,ssh}")
+	command.PersistentFlags().String(core.SignKeyPassphraseEnvFlag, "", "Env var containing the passphrase of the signing key")
+	command.PersistentFlags().Bool(core.TagFlag, false, "Create the annotated tag <image>/<tag> on the commit")
+	command.PersistentFlags().Bool(core.NoPushFlag, false, "Commit without pushing to the remote")
+	command.PersistentFlags().String(core.RemoteFlag, git.DefaultRemoteName, "Name of the git remote to push to")
+	command.PersistentFlags().String(core.RefSpecFlag, "", "Refspec of the push, the current branch by default, e.g refs/heads/main:refs/heads/main")
+	command.PersistentFlags().String(core.SSHKeyFlag, "", "Private key file of the ssh remotes, the ssh agent is used by default")
+	command.PersistentFlags().String(core.SSHKeyPassphraseEnvFlag, "", "Env var containing the passphrase of the ssh key")
+	command.PersistentFlags().String(core.KnownHostsFlag, "", "known_hosts file of the ssh remotes, $SSH_KNOWN_HOSTS or ~/.ssh/known_hosts by default")
+	command.PersistentFlags().Bool(core.InsecureIgnoreHostKeyFlag, false, "Do not verify the host key of the ssh remotes")
+	command.PersistentFlags().String(core.GitUsernameFlag, "", "Username of the https remotes, git by default")
+	command.PersistentFlags().String(core.GitTokenEnvFlag, "", "Env var containing the token or password of the https remotes")
+	command.PersistentFlags().Bool(core.PullRequestFlag, false, "Push the commit to a new branch and open a pull request instead of pushing to the current branch")
+	command.PersistentFlags().String(core.PullRequestProviderFlag, "", "Pull request provider {github,gitlab}, detected from the remote host by default")
+	command.PersistentFlags().String(core.PullRequestAPIURLFlag, "", "API URL of the pull request provider, derived from the remote host by default")
 
 	if err := command.MarkPersistentFlagRequired(core.NameFlag); err != nil {
-		return nil, err
+		return err
 	}
 	if err := command.MarkPersistentFlagRequired(core.EmailFlag); err != nil {
-		return nil, err
+		return err
 	}
 
-	return command, nil
+	return nil
 }
 
 func getGitAuthorInfo(flagSet *pflag.FlagSet) (string, string, error) {
@@ -57,19 +97,183 @@ func getGitAuthorInfo(flagSet *pflag.FlagSet) (string, string, error) {
 	return name, email, nil
 }
 
+type allOptions struct {
+	enabled     bool
+	extraFiles  []string
+	pushRetries int
+}
+
+func getAllOptions(flagSet *pflag.FlagSet) (allOptions, error) {
+	var opt allOptions
+	var err error
+
+	if opt.extraFiles, err = flagSet.GetStringSlice(core.ExtraFileFlag); err != nil {
+		return opt, err
+	}
+	// The bulk mode flags are only defined by the commit command.
+	if flagSet.Lookup(core.AllFlag) == nil {
+		return opt, nil
+	}
+	if opt.enabled, err = flagSet.GetBool(core.AllFlag); err != nil {
+		return opt, err
+	}
+	if opt.pushRetries, err = flagSet.GetInt(core.PushRetriesFlag); err != nil {
+		return opt, err
+	}
+
+	return opt, nil
+}
+
+type signOptions struct {
+	tag  bool
+	sign util.GitSignOptions
+}
+
+func getSignOptions(flagSet *pflag.FlagSet) (signOptions, error) {
+	var opt signOptions
+	var err error
+
+	if opt.tag, err = flagSet.GetBool(core.TagFlag); err != nil {
+		return opt, err
+	}
+	if opt.sign.KeyFile, err = flagSet.GetString(core.SignKeyFlag); err != nil {
+		return opt, err
+	}
+	if opt.sign.Format, err = flagSet.GetString(core.SignFormatFlag); err != nil {
+		return opt, err
+	}
+	if opt.sign.PassphraseEnv, err = flagSet.GetString(core.SignKeyPassphraseEnvFlag); err != nil {
+		return opt, err
+	}
+
+	return opt, nil
+}
+
+type pushOptions struct {
+	noPush  bool
+	remote  string
+	refSpec string
+	auth    util.GitAuthOptions
+}
+
+func getPushOptions(flagSet *pflag.FlagSet) (pushOptions, error) {
+	var opt pushOptions
+	var err error
+
+	if opt.noPush, err = flagSet.GetBool(core.NoPushFlag); err != nil {
+		return opt, err
+	}
+	if opt.remote, err = flagSet.GetString(core.RemoteFlag); err != nil {
+		return opt, err
+	}
+	if opt.refSpec, err = flagSet.GetString(core.RefSpecFlag); err != nil {
+		return opt, err
+	}
+	if opt.auth.SSHKeyFile, err = flagSet.GetString(core.SSHKeyFlag); err != nil {
+		return opt, err
+	}
+	if opt.auth.SSHKeyPassphraseEnv, err = flagSet.GetString(core.SSHKeyPassphraseEnvFlag); err != nil {
+		return opt, err
+	}
+	if opt.auth.KnownHostsFile, err = flagSet.GetString(core.KnownHostsFlag); err != nil {
+		return opt, err
+	}
+	if opt.auth.InsecureIgnoreHostKey, err = flagSet.GetBool(core.InsecureIgnoreHostKeyFlag); err != nil {
+		return opt, err
+	}
+	if opt.auth.Username, err = flagSet.GetString(core.GitUsernameFlag); err != nil {
+		return opt, err
+	}
+	if opt.auth.TokenEnv, err = flagSet.GetString(core.GitTokenEnvFlag); err != nil {
+		return opt, err
+	}
+
+	return opt, nil
+}
+
+type pullRequestOptions struct {
+	enabled  bool
+	provider pullrequest.Provider
+	apiURL   string
+}
+
+func getPullRequestOptions(flagSet *pflag.FlagSet) (pullRequestOptions, error) {
+	var opt pullRequestOptions
+	var provider string
+	var err error
+
+	if opt.enabled, err = flagSet.GetBool(core.PullRequestFlag); err != nil {
+		return opt, err
+	}
+	if provider, err = flagSet.GetString(core.PullRequestProviderFlag); err != nil {
+		return opt, err
+	}
+	opt.provider = pullrequest.Provider(provider)
+	if opt.apiURL, err = flagSet.GetString(core.PullRequestAPIURLFlag); err != nil {
+		return opt, err
+	}
+
+	return opt, nil
+}
+
 func commit(command *cobra.Command, args []string) error {
 	flagSet := command.Flags()
 
+	opt, err := getOptions(flagSet)
+	if err != nil {
+		return err
+	}
+	log.Info().Bool(core.EnabledKey, opt.dryRun).Msg(core.DryRunFlag)
+
+	return commitImage(flagSet, opt)
+}
+
+// commitImage commits the build file of the image of the options, or of
+// all the images with --all.
+func commitImage(flagSet *pflag.FlagSet, opt CommonOptions) error {
 	name, email, err := getGitAuthorInfo(flagSet)
 	if err != nil {
 		return err
 	}
 
-	opt, err := getOptions(flagSet)
+	pushOpt, err := getPushOptions(flagSet)
 	if err != nil {
 		return err
 	}
-	log.Info().Bool(core.EnabledKey, opt.dryRun).Msg(core.DryRunFlag)
+	prOpt, err := getPullRequestOptions(flagSet)
+	if err != nil {
+		return err
+	}
+	signOpt, err := getSignOptions(flagSet)
+	if err != nil {
+		return err
+	}
+	if signOpt.tag && prOpt.enabled {
+		return fmt.Errorf("--%s can't be used with --%s, the commit is not on the current branch", core.TagFlag, core.PullRequestFlag)
+	}
+	signer, err := util.NewGitSigner(signOpt.sign)
+	if err != nil {
+		return err
+	}
+	author := &object.Signature{
+		Name:  name,
+		Email: email,
+		When:  time.Now(),
+	}
+
+	allOpt, err := getAllOptions(flagSet)
+	if err != nil {
+		return err
+	}
+	if allOpt.enabled {
+		if prOpt.enabled {
+			return fmt.Errorf("--%s can't be used with --%s", core.AllFlag, core.PullRequestFlag)
+		}
+		return commitAll(flagSet, opt, allOpt, pushOpt, signOpt, signer, author)
+	}
+	if opt.imageName == "" {
+		return fmt.Errorf(`required flag(s) "%s" not set`, core.ImageFlag)
+	}
 
 	if _, err := os.Stat(opt.buildFilePath); os.IsNotExist(err) {
 		log.Warn().Str(core.FileKey, opt.buildFilePath).
@@ -118,64 +322,472 @@ func commit(command *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Println(buildFileRelPath)
-	if unmod := util.GitIsFileUnmodifiedWorktree(status, buildFileRelPath); !unmod {
-		log.Info().Str(core.FileKey, buildFileRelPath).
-			Msg("add file content to the index")
-		if !opt.dryRun {
-			if _, err := wt.Add(buildFileRelPath); err != nil {
-				return err
-			}
+	paths, err := getImageCommitPaths(status, root, opt.imageDir, opt.buildFilePath, allOpt.extraFiles)
+	if err != nil {
+		return err
+	}
+	reportUncommittedFiles(status, paths)
+
+	// The commit of the pull request is created from the worktree files,
+	// the index is left unchanged.
+	for _, p := range paths {
+		if prOpt.enabled {
+			log.Info().Str(core.FileKey, p).Msg("add file content to the pull request")
+			continue
 		}
+		log.Info().Str(core.FileKey, p).Msg("add file content to the index")
+	}
+	if !opt.dryRun && !prOpt.enabled {
+		if err := stageFiles(wt, status, paths); err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msg("create new commit")
+
+	msg, err := newCommitMessage(flagSet, opt, repo, buildFileRelPath)
+	if err != nil {
+		return err
 	}
+	log.Info().Str(core.MsgKey, msg).Msg("create commit message")
 
-	if unmod := util.GitIsFileUnmodifiedStaging(status, buildFileRelPath); !unmod {
-		log.Info().Msg("create new commit")
+	if !opt.dryRun {
+		if prOpt.enabled {
+			return commitPullRequest(repo, signer, opt, pushOpt, prOpt, buildFileRelPath, paths, msg, author)
+		}
 
-		msg, err := newCommitMessage(opt)
+		hash, err := commitBuildFile(repo, wt, signer, msg, author)
 		if err != nil {
 			return err
 		}
-		log.Info().Str(core.MsgKey, msg).Msg("create commit message")
-
-		if !opt.dryRun {
-			commit, err := wt.Commit(msg, &git.CommitOptions{
-				Author: &object.Signature{
-					Name:  name,
-					Email: email,
-					When:  time.Now(),
-				},
-			})
+
+		refSpec := config.RefSpec(pushOpt.refSpec)
+		if refSpec == "" {
+			head, err := repo.Head()
 			if err != nil {
 				return err
 			}
-			log.Info().Str(core.HashKey, commit.String()).Msg("")
+			refSpec = config.RefSpec(fmt.Sprintf("%s:%s", head.Name(), head.Name()))
+		}
+		refSpecs := []config.RefSpec{refSpec}
 
-			obj, err := repo.CommitObject(commit)
+		if signOpt.tag {
+			build, err := core.NewBuildFromManifest(opt.buildFilePath)
 			if err != nil {
 				return err
 			}
-			log.Info().Str(core.CommitKey, obj.String()).Msg("")
-
-			log.Info().Msg("push to remote")
-			if err = repo.Push(&git.PushOptions{}); err != nil {
+			tag, err := createBuildTag(repo, signer, build, hash, msg, author)
+			if err != nil {
 				return err
 			}
+			refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("%s:%s", tag.Name(), tag.Name())))
 		}
 
+		if err := pushToRemote(repo, pushOpt, refSpecs...); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func newCommitMessage(opt CommonOptions) (string, error) {
-	var msg string
+// pushToRemote pushes the refspecs to the remote with the auth method
+// matching the remote URL, nothing is pushed with --no-push.
+func pushToRemote(repo *git.Repository, opt pushOptions, refSpecs ...config.RefSpec) error {
+	if opt.noPush {
+		log.Info().Str(core.RemoteKey, opt.remote).Msg("push to remote disabled")
+		return nil
+	}
+	var specs []string
+	for _, refSpec := range refSpecs {
+		if err := refSpec.Validate(); err != nil {
+			return fmt.Errorf("invalid refspec %s: %w", refSpec, err)
+		}
+		specs = append(specs, refSpec.String())
+	}
+
+	remoteURL, auth, method, err := getRemoteAuth(repo, opt)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str(core.RemoteKey, opt.remote).
+		Strs(core.RefSpecKey, specs).
+		Str(core.AuthKey, method).
+		Msg("push to remote")
+	err = repo.Push(&git.PushOptions{
+		RemoteName: opt.remote,
+		RefSpecs:   refSpecs,
+		Auth:       auth,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		log.Info().Str(core.RemoteKey, opt.remote).Msg("remote already up to date")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("push to remote %s (%s) with %s failed: %w", opt.remote, remoteURL, method, err)
+	}
+	return nil
+}
+
+// getImageCommitPaths returns the modified build file of the image with
+// the modified files matching spec.commitFiles and the extra patterns.
+func getImageCommitPaths(status git.Status, root, imageDir, buildFilePath string, extraFiles []string) ([]string, error) {
+	build, err := core.NewBuildFromManifest(buildFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	imageRelDir, err := util.GetRelPathFromPathInTree(root, imageDir)
+	if err != nil {
+		return nil, err
+	}
+	patterns := append([]string{filepath.Base(buildFilePath)}, build.Spec.CommitFiles...)
+	patterns = append(patterns, extraFiles...)
+
+	paths, err := util.GitMatchModifiedFiles(status, imageRelDir, patterns)
+	if err != nil {
+		return nil, fmt.Errorf("image %s: %w", build.Image.Name, err)
+	}
+	return paths, nil
+}
+
+// reportUncommittedFiles logs the modified files which are not committed.
+func reportUncommittedFiles(status git.Status, paths []string) {
+	committed := map[string]bool{}
+	for _, p := range paths {
+		committed[p] = true
+	}
+
+	var files []string
+	for file := range status {
+		if !committed[file] && isFileModified(status, file) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		log.Warn().Str(core.FileKey, file).Msg("uncommitted change")
+	}
+}
+
+// stageFiles adds the files to the index, the deleted files are removed
+// from the index.
+func stageFiles(wt *git.Worktree, status git.Status, paths []string) error {
+	for _, p := range paths {
+		if status.File(p).Worktree == git.Deleted {
+			if _, err := wt.Remove(p); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := wt.Add(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getRemoteAuth returns the URL of the remote and the auth method
+// matching the URL.
+func getRemoteAuth(repo *git.Repository, opt pushOptions) (string, transport.AuthMethod, string, error) {
+	remote, err := repo.Remote(opt.remote)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("git remote %s: %w", opt.remote, err)
+	}
+	if len(remote.Config().URLs) == 0 {
+		return "", nil, "", fmt.Errorf("git remote %s has no url", opt.remote)
+	}
+	remoteURL := remote.Config().URLs[0]
+
+	auth, method, err := util.NewGitAuth(remoteURL, opt.auth)
+	if err != nil {
+		return "", nil, "", err
+	}
+	return remoteURL, auth, method, nil
+}
+
+func commitBuildFile(repo *git.Repository, wt *git.Worktree, signer *util.GitSigner, msg string, author *object.Signature) (plumbing.Hash, error) {
+	commit, err := signer.Commit(repo, wt, msg, &git.CommitOptions{Author: author})
+	if err != nil {
+		return commit, err
+	}
+	log.Info().Str(core.HashKey, commit.String()).Msg("")
+
+	obj, err := repo.CommitObject(commit)
+	if err != nil {
+		return commit, err
+	}
+	log.Info().Str(core.CommitKey, obj.String()).Msg("")
+
+	return commit, nil
+}
+
+// createBuildTag creates the annotated tag <image>/<tag> of the commit
+// of the build file.
+func createBuildTag(repo *git.Repository, signer *util.GitSigner, build *core.Build,
+	hash plumbing.Hash, msg string, tagger *object.Signature) (*plumbing.Reference, error) {
+
+	name := buildTagName(build)
+	tag, err := signer.CreateTag(repo, name, hash, &git.CreateTagOptions{
+		Tagger:  tagger,
+		Message: msg,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create tag %s: %w", name, err)
+	}
+	log.Info().Str(core.TagKey, name).Str(core.HashKey, tag.Hash().String()).Msg("create tag")
+
+	return tag, nil
+}
+
+// buildTagName returns the name of the tag of a build, <image>/<tag>.
+func buildTagName(build *core.Build) string {
+	return build.Image.Name + "/" + build.Image.Tag
+}
+
+// commitPullRequest commits the files to a branch named after the image
+// and its new tag, pushes the branch and opens a pull request against the
+// current branch. The commit is created from the worktree files on top of
+// the current commit, HEAD, the index and the worktree are not changed.
+func commitPullRequest(repo *git.Repository, signer *util.GitSigner, opt CommonOptions, pushOpt pushOptions, prOpt pullRequestOptions,
+	buildFileRelPath string, paths []string, msg string, author *object.Signature) error {
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("a branch must be checked out to open a pull request: %s", head.Name())
+	}
+
 	build, err := core.NewBuildFromManifest(opt.buildFilePath)
 	if err != nil {
-		return msg, err
+		return err
+	}
+	oldBuild, err := getBuildFromCommit(repo, head.Hash(), buildFileRelPath)
+	if err != nil {
+		return err
+	}
+
+	remote, err := repo.Remote(pushOpt.remote)
+	if err != nil {
+		return fmt.Errorf("git remote %s: %w", pushOpt.remote, err)
+	}
+	if len(remote.Config().URLs) == 0 {
+		return fmt.Errorf("git remote %s has no url", pushOpt.remote)
+	}
+	remoteRepo, err := util.ParseGitRemoteURL(remote.Config().URLs[0])
+	if err != nil {
+		return err
+	}
+	client, err := pullrequest.New(remoteRepo, prOpt.provider, prOpt.apiURL)
+	if err != nil {
+		return err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	tree, err := util.GitWriteTree(repo, wt.Filesystem.Root(), head.Hash(), paths)
+	if err != nil {
+		return err
+	}
+	hash, err := signer.CommitTree(repo, tree, []plumbing.Hash{head.Hash()}, msg, author)
+	if err != nil {
+		return err
+	}
+
+	// The branch is reset to the new commit if it already exists.
+	branch := plumbing.NewBranchReferenceName(pullrequest.BranchName(build.Image.Name, build.Image.Tag))
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, hash)); err != nil {
+		return err
+	}
+	log.Info().Str(core.BranchKey, branch.Short()).Str(core.HashKey, hash.String()).Msg("create pull request branch")
+
+	if pushOpt.noPush {
+		log.Info().Str(core.BranchKey, branch.Short()).Msg("push disabled, no pull request opened")
+		return nil
+	}
+	// The branch is forced as it is reset on each run.
+	refSpec := config.RefSpec(fmt.Sprintf("+%s:%s", branch, branch))
+	if err := pushToRemote(repo, pushOpt, refSpec); err != nil {
+		return err
+	}
+
+	url, err := client.Open(&pullrequest.PullRequest{
+		Head:  branch.Short(),
+		Base:  head.Name().Short(),
+		Title: strings.SplitN(msg, "\n", 2)[0],
+		Body:  pullrequest.NewBody(oldBuild, build),
+	})
+	if err != nil {
+		return err
 	}
+	log.Info().Str(core.URLKey, url).Msg("pull request opened")
 
-	msg = fmt.Sprintf("[gojo] New build file, image=%s, tag=%s", build.Image.Name, build.Image.Tag)
+	return nil
+}
+
+// pullRequestBranchExists returns true with --pull-request if the branch of
+// the new tag of the image exists locally or on the remote, the update is
+// then already proposed.
+func pullRequestBranchExists(flagSet *pflag.FlagSet, opt CommonOptions, build *core.Build) (bool, string, error) {
+	prOpt, err := getPullRequestOptions(flagSet)
+	if err != nil || !prOpt.enabled {
+		return false, "", err
+	}
+	pushOpt, err := getPushOptions(flagSet)
+	if err != nil {
+		return false, "", err
+	}
 
-	return msg, nil
+	branch := plumbing.NewBranchReferenceName(pullrequest.BranchName(build.Image.Name, build.Image.Tag))
+	repo, err := util.OpenGitRepo(opt.imageDir)
+	if err != nil {
+		return false, "", err
+	}
+	if _, err := repo.Reference(branch, false); err == nil {
+		return true, branch.Short(), nil
+	} else if err != plumbing.ErrReferenceNotFound {
+		return false, "", err
+	}
+	if pushOpt.noPush {
+		return false, branch.Short(), nil
+	}
+
+	remote, err := repo.Remote(pushOpt.remote)
+	if err != nil {
+		return false, "", fmt.Errorf("git remote %s: %w", pushOpt.remote, err)
+	}
+	_, auth, _, err := getRemoteAuth(repo, pushOpt)
+	if err != nil {
+		return false, "", err
+	}
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err == transport.ErrEmptyRemoteRepository {
+		return false, branch.Short(), nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("list git remote %s: %w", pushOpt.remote, err)
+	}
+	for _, ref := range refs {
+		if ref.Name() == branch {
+			return true, branch.Short(), nil
+		}
+	}
+	return false, branch.Short(), nil
+}
+
+// getBuildFromCommit returns the build file of a commit, nil if the file
+// does not exist in the commit.
+func getBuildFromCommit(repo *git.Repository, hash plumbing.Hash, relPath string) (*core.Build, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := commit.File(relPath)
+	if err == object.ErrFileNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return core.DecodeBuild([]byte(contents))
+}
+
+// newCommitMessage renders the commit message template with the build
+// file of the HEAD commit and the new build file.
+func newCommitMessage(flagSet *pflag.FlagSet, opt CommonOptions, repo *git.Repository, buildFileRelPath string) (string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	data, err := newCommitMessageData(repo, head.Hash(), opt.buildFilePath, buildFileRelPath)
+	if err != nil {
+		return "", err
+	}
+	setChangelogs(flagSet, data.New.Spec.Sources, data.Changes)
+
+	text, err := getMessageTemplate(flagSet, core.DefaultCommitMessageTemplate)
+	if err != nil {
+		return "", err
+	}
+	return core.NewCommitMessage(text, data)
+}
+
+// newCommitMessageData diffs the build file with the build file of the
+// commit.
+func newCommitMessageData(repo *git.Repository, hash plumbing.Hash, buildFilePath, buildFileRelPath string) (*core.CommitMessageData, error) {
+	build, err := core.NewBuildFromManifest(buildFilePath)
+	if err != nil {
+		return nil, err
+	}
+	oldBuild, err := getBuildFromCommit(repo, hash, buildFileRelPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &core.CommitMessageData{
+		Old:     oldBuild,
+		New:     build,
+		Changes: core.DiffFacts(oldBuild, build),
+	}, nil
+}
+
+// getMessageTemplate returns the template of --message-template, the
+// default template otherwise.
+func getMessageTemplate(flagSet *pflag.FlagSet, defaultText string) (string, error) {
+	templatePath, err := flagSet.GetString(core.MessageTemplateFlag)
+	if err != nil {
+		return "", err
+	}
+	if templatePath == "" {
+		return defaultText, nil
+	}
+
+	data, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// setChangelogs sets the release notes and the compare URLs of the changed
+// version facts, the changelogs are optional so the errors are logged.
+func setChangelogs(flagSet *pflag.FlagSet, sources []core.Source, changes []*core.FactChange) {
+	for _, change := range changes {
+		if change.Kind != core.VersionFactKind || change.Source == "" {
+			continue
+		}
+		for _, src := range sources {
+			if src.Name != change.Source {
+				continue
+			}
+			prvdr, err := provider.New(flagSet, src)
+			if err != nil {
+				log.Warn().AnErr(core.ErrKey, err).Str(core.NameKey, src.Name).Msg("changelog")
+				continue
+			}
+			changeloger, ok := prvdr.(provider.Changeloger)
+			if !ok {
+				continue
+			}
+			changelog, err := changeloger.GetChangelog(change.OldRaw, change.NewRaw)
+			if err != nil {
+				log.Warn().AnErr(core.ErrKey, err).Str(core.NameKey, src.Name).Msg("changelog")
+				continue
+			}
+			change.ReleaseNotes = changelog.Notes
+			change.CompareURL = changelog.CompareURL
+		}
+	}
 }
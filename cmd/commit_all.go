@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+// commitAll commits the modified build files of all the images, with the
+// files of spec.commitFiles, in a single commit pushed once. The commit is rebased on
+// the remote branch and pushed again when the push is rejected.
+func commitAll(flagSet *pflag.FlagSet, opt CommonOptions, allOpt allOptions, pushOpt pushOptions,
+	signOpt signOptions, signer *util.GitSigner, author *object.Signature) error {
+
+	imagesDir, err := filepath.Abs(opt.imagesDir)
+	if err != nil {
+		return err
+	}
+	repo, err := util.OpenGitRepo(imagesDir)
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	if !head.Name().IsBranch() {
+		return fmt.Errorf("a branch must be checked out to commit all the images: %s", head.Name())
+	}
+
+	entries, err := ioutil.ReadDir(imagesDir)
+	if err != nil {
+		return err
+	}
+
+	root := wt.Filesystem.Root()
+	var paths []string
+	data := &core.BulkCommitMessageData{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		imageDir := filepath.Join(imagesDir, entry.Name())
+		buildFilePath := filepath.Join(imageDir, opt.buildFileName)
+		if _, err := os.Stat(buildFilePath); os.IsNotExist(err) {
+			continue
+		}
+
+		buildFileRelPath, err := util.GetRelPathFromPathInTree(root, buildFilePath)
+		if err != nil {
+			return err
+		}
+		if !isFileModified(status, buildFileRelPath) {
+			continue
+		}
+
+		imagePaths, err := getImageCommitPaths(status, root, imageDir, buildFilePath, allOpt.extraFiles)
+		if err != nil {
+			return err
+		}
+
+		msgData, err := newCommitMessageData(repo, head.Hash(), buildFilePath, buildFileRelPath)
+		if err != nil {
+			return fmt.Errorf("image %s: %w", entry.Name(), err)
+		}
+		log.Info().Str(core.ImageKey, entry.Name()).Strs(core.FileKey, imagePaths).Msg("add image to the commit")
+		data.Images = append(data.Images, msgData)
+		paths = append(paths, imagePaths...)
+	}
+
+	if len(data.Images) == 0 {
+		log.Info().Str(core.RepoKey, imagesDir).Msg("nothing to commit, no build file modified")
+		return nil
+	}
+	reportUncommittedFiles(status, paths)
+
+	text, err := getMessageTemplate(flagSet, core.DefaultBulkCommitMessageTemplate)
+	if err != nil {
+		return err
+	}
+	msg, err := core.NewCommitMessage(text, data)
+	if err != nil {
+		return err
+	}
+	log.Info().Int(core.CountKey, len(data.Images)).Str(core.MsgKey, msg).Msg("create commit message")
+
+	if opt.dryRun {
+		return nil
+	}
+
+	if err := stageFiles(wt, status, paths); err != nil {
+		return err
+	}
+	hash, err := commitBuildFile(repo, wt, signer, msg, author)
+	if err != nil {
+		return err
+	}
+
+	refSpec := config.RefSpec(pushOpt.refSpec)
+	if refSpec == "" {
+		refSpec = config.RefSpec(fmt.Sprintf("%s:%s", head.Name(), head.Name()))
+	}
+	if err := refSpec.Validate(); err != nil {
+		return fmt.Errorf("invalid refspec %s: %w", refSpec, err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		refSpecs := []config.RefSpec{refSpec}
+		if signOpt.tag {
+			tagRefSpecs, err := createBuildTags(repo, signer, data.Images, hash, msg, author)
+			if err != nil {
+				return err
+			}
+			refSpecs = append(refSpecs, tagRefSpecs...)
+		}
+
+		err := pushToRemote(repo, pushOpt, refSpecs...)
+		if err == nil || !isNonFastForward(err) || attempt >= allOpt.pushRetries {
+			return err
+		}
+		log.Warn().AnErr(core.ErrKey, err).Int(core.CountKey, attempt+1).Msg("push rejected, rebase on the remote branch")
+
+		if signOpt.tag {
+			for _, image := range data.Images {
+				if err := repo.DeleteTag(buildTagName(image.New)); err != nil {
+					return err
+				}
+			}
+		}
+		if hash, err = rebaseOnRemote(repo, wt, signer, pushOpt, refSpec.Dst(head.Name()), paths, msg, author); err != nil {
+			return err
+		}
+	}
+}
+
+// createBuildTags creates the tags of the images and returns their
+// refspecs.
+func createBuildTags(repo *git.Repository, signer *util.GitSigner, images []*core.CommitMessageData,
+	hash plumbing.Hash, msg string, tagger *object.Signature) ([]config.RefSpec, error) {
+
+	var refSpecs []config.RefSpec
+	for _, image := range images {
+		tag, err := createBuildTag(repo, signer, image.New, hash, msg, tagger)
+		if err != nil {
+			return nil, err
+		}
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("%s:%s", tag.Name(), tag.Name())))
+	}
+	return refSpecs, nil
+}
+
+// rebaseOnRemote fetches the remote branch and commits the files again on
+// top of it.
+func rebaseOnRemote(repo *git.Repository, wt *git.Worktree, signer *util.GitSigner, opt pushOptions,
+	branch plumbing.ReferenceName, paths []string, msg string, author *object.Signature) (plumbing.Hash, error) {
+
+	remoteURL, auth, method, err := getRemoteAuth(repo, opt)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	remoteBranch := plumbing.NewRemoteReferenceName(opt.remote, branch.Short())
+	refSpec := config.RefSpec(fmt.Sprintf("+%s:%s", branch, remoteBranch))
+	log.Info().Str(core.RemoteKey, opt.remote).Str(core.RefSpecKey, refSpec.String()).Msg("fetch remote branch")
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: opt.remote,
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return plumbing.ZeroHash, fmt.Errorf("fetch from remote %s (%s) with %s failed: %w", opt.remote, remoteURL, method, err)
+	}
+
+	remoteRef, err := repo.Reference(remoteBranch, true)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if commit.NumParents() != 1 {
+		return plumbing.ZeroHash, fmt.Errorf("commit %s can't be rebased, parents=%d", commit.Hash, commit.NumParents())
+	}
+
+	if err := util.GitRebaseFiles(repo, wt, commit.ParentHashes[0], remoteRef.Hash(), paths); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return commitBuildFile(repo, wt, signer, msg, author)
+}
+
+// isFileModified returns true if the file has changes in the worktree or
+// in the index.
+func isFileModified(status git.Status, relPath string) bool {
+	if _, ok := status[relPath]; !ok {
+		return false
+	}
+	return !util.GitIsFileClean(status, relPath)
+}
+
+// isNonFastForward returns true if the push is rejected because the
+// remote branch has new commits.
+func isNonFastForward(err error) bool {
+	return strings.Contains(err.Error(), "non-fast-forward") || strings.Contains(err.Error(), "fetch first")
+}
@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/metrics"
 	"github.com/spiarh/gojo/pkg/provider"
 	"github.com/spiarh/gojo/pkg/util"
 )
@@ -21,12 +25,14 @@ gojo versions find --image-dir ~/image-git-dir --image nextcloud
 		PersistentPreRunE: SetGlobalLogLevel,
 	}
 
-	if err := AddCommonPersistentFlags(listCommand); err != nil {
+	if err := AddCommonPersistentFlags(listCommand, true); err != nil {
 		return nil, err
 	}
-	if err := AddCommonPersistentFlags(getCommand); err != nil {
+	if err := AddCommonPersistentFlags(getCommand, true); err != nil {
 		return nil, err
 	}
+	AddMetricsFlags(listCommand)
+	AddMetricsFlags(getCommand)
 
 	command.AddCommand(listCommand)
 	command.AddCommand(getCommand)
@@ -55,64 +61,127 @@ func facts(command *cobra.Command, args []string) error {
 	}
 
 	log.Info().Bool(core.EnabledKey, opt.dryRun).Msg(core.DryRunFlag)
+	defer writeMetricsFile(flagSet)
 
+	oldBuild, err := core.NewBuildFromManifest(opt.buildFilePath)
+	if err != nil {
+		return err
+	}
 	build, err := core.NewBuildFromManifest(opt.buildFilePath)
 	if err != nil {
 		return err
 	}
 
-	// Manage facts
-	if len(build.Spec.Sources) != 0 {
-		if err := build.ValidatePreProcess(); err != nil {
-			log.Fatal().AnErr(core.ErrKey, err).Msg("")
-		}
-
-		if err = setFacts(flagSet, build.Spec.Facts, build.Spec.Sources); err != nil {
-			log.Fatal().AnErr(core.ErrKey, err).Msg("retrieve facts")
-		}
-	} else {
-		log.Warn().Msg("no value sources defined, no facts to search")
+	if err := updateFacts(flagSet, build); err != nil {
+		return err
 	}
+	setUpdateAvailable(opt.imageName, len(core.DiffFacts(oldBuild, build)) != 0)
 
 	if opt.dryRun || (action == core.ListAction) {
 		return nil
 	}
 
-	log.Info().Msg("build image tag")
-	if build.Image.Tag, err = core.BuildTag(build.Spec.Facts, build.Spec.TagFormat, build.Image.Context); err != nil {
-		return err
-	}
-	log.Info().
-		Str("tag", build.Image.Tag).
-		Msg("image tag")
-
 	return build.WriteToFile(build.Image.BuildfilePath)
 }
 
 func setFacts(flagSet *pflag.FlagSet, facts []*core.Fact, sources []core.Source) error {
+	// Providers are shared by the facts of a same source
+	// so the upstream data is only retrieved once.
+	providers := make(map[string]provider.Provider)
+	filters := make(map[string]*provider.VersionFilter)
+
+	// The sources only follow the facts resolved by this run, the values
+	// of the build file may be outdated.
+	sorted, err := core.SortFactsByReferences(facts, sources)
+	if err != nil {
+		return err
+	}
+	var resolved []*core.Fact
 	for _, fact := range facts {
+		if fact.Source == "" {
+			resolved = append(resolved, fact)
+		}
+	}
+
+	for _, fact := range sorted {
 		if fact.Source == "" {
 			continue
 		}
 		for _, src := range sources {
 			if fact.Source == src.Name {
-				repo, err := provider.New(flagSet, src)
-				if err != nil {
-					return err
+				providerType := string(provider.SourceType(src))
+				repo, ok := providers[src.Name]
+				if !ok {
+					if err := src.SetFactReferences(resolved); err != nil {
+						return err
+					}
+					var err error
+					if repo, err = provider.New(flagSet, src); err != nil {
+						metrics.FactErrors.Inc(providerType)
+						return err
+					}
+					providers[src.Name] = repo
+					if filters[src.Name], err = provider.NewVersionFilter(src); err != nil {
+						return err
+					}
 				}
 
-				if fact.Value, err = repo.GetLatest(fact.Semver); err != nil {
+				start := time.Now()
+				var err error
+				fact.Value, err = repo.GetFact(fact)
+				metrics.FactDuration.Observe(time.Since(start).Seconds(), providerType)
+				if err != nil {
+					metrics.FactErrors.Inc(providerType)
 					return err
 				}
 
 				if fact.Kind == core.VersionFactKind {
-					fact.Value = util.SanitizeVersion(fact.Value)
+					raw := fact.Value
+					filter := filters[src.Name]
+					if !filter.Match(raw) {
+						metrics.FactErrors.Inc(providerType)
+						return fmt.Errorf("version not selected by the filter of source %s: %s", src.Name, raw)
+					}
+					fact.Value = util.SanitizeVersion(filter.Transform(raw))
+
+					// The raw version is kept for the downloads.
+					fact.RawValue = ""
+					if raw != fact.Value {
+						fact.RawValue = raw
+					}
 				}
 			}
 		}
 		if fact.Value == "" {
-			log.Fatal().Msgf("no value found for fact with name: %s", fact.Name)
+			return fmt.Errorf("no value found for fact with name: %s", fact.Name)
+		}
+		resolved = append(resolved, fact)
+	}
+	return nil
+}
+
+// updateFacts sets the latest values of the facts, the tags of the from
+// images and the tag of the image.
+func updateFacts(flagSet *pflag.FlagSet, build *core.Build) error {
+	if len(build.Spec.Sources) == 0 {
+		log.Warn().Msg("no value sources defined, no facts to search")
+	} else {
+		if err := build.ValidatePreProcess(); err != nil {
+			return err
+		}
+		if err := setFacts(flagSet, build.Spec.Facts, build.Spec.Sources); err != nil {
+			return fmt.Errorf("retrieve facts: %w", err)
+		}
+		if err := build.SetFromImagesTags(); err != nil {
+			return fmt.Errorf("set from images tags: %w", err)
 		}
 	}
+
+	var err error
+	if build.Image.Tag, err = core.BuildTag(build.Spec.Facts, build.Spec.TagFormat, build.Image.Context); err != nil {
+		return err
+	}
+	log.Info().Str("tag", build.Image.Tag).Msg("image tag")
+
 	return nil
 }
@@ -22,7 +22,7 @@ func Scaffold() (*cobra.Command, error) {
 		PersistentPreRunE: SetGlobalLogLevel,
 	}
 
-	if err := AddCommonPersistentFlags(command); err != nil {
+	if err := AddCommonPersistentFlags(command, true); err != nil {
 		return nil, err
 	}
 
@@ -168,6 +168,7 @@ func addAlpineProvider(build *core.Build, flagSet *pflag.FlagSet) error {
 	build.Spec.Sources = []core.Source{source}
 
 	fact := core.NewFact(core.VersionKey, "", core.Alpine, core.VersionFactKind)
+	fact.VersionScheme = core.APKVersionScheme
 	build.Spec.BuildArgs = append(build.Spec.BuildArgs, core.VersionKey)
 	build.Spec.Facts = append(build.Spec.Facts, fact)
 	build.Spec.TagFormat = core.TagFormatVersion
@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/metrics"
+	"github.com/spiarh/gojo/pkg/schedule"
+	"github.com/spiarh/gojo/pkg/webhook"
+)
+
+func Serve() (*cobra.Command, error) {
+	var command = &cobra.Command{
+		Use:   "serve",
+		Short: "Update the images of the images directory on their schedules",
+		Example: `gojo serve podman --name gojo --email gojo@example.com
+gojo serve buildah --name gojo --email gojo@example.com --concurrency 2 --default-schedule '0 */6 * * *'`,
+		SilenceUsage:      true,
+		TraverseChildren:  true,
+		PersistentPreRunE: SetGlobalLogLevel,
+	}
+
+	// All the images are served unless --image is set.
+	if err := addUpdateCommands(command, serve, false); err != nil {
+		return nil, err
+	}
+	for _, mgrCommand := range command.Commands() {
+		mgrCommand.PersistentFlags().Int(core.ConcurrencyFlag, 1, "Maximum number of images updated at the same time")
+		mgrCommand.PersistentFlags().String(core.DefaultScheduleFlag, "1h", "Schedule of the images without spec.schedule, an interval (1h) or a cron expression (0 * * * *)")
+		mgrCommand.PersistentFlags().Duration(core.BackoffFlag, time.Minute, "Delay before retrying a failed update, doubled on each failure")
+		mgrCommand.PersistentFlags().Duration(core.MaxBackoffFlag, time.Hour, "Maximum delay before retrying a failed update")
+		mgrCommand.PersistentFlags().String(core.ListenFlag, "", "Address of the server of the metrics and of the webhooks updating the images on the GitHub and GitLab releases and tags, e.g :8080")
+		mgrCommand.PersistentFlags().String(core.WebhookSecretEnvFlag, "GOJO_WEBHOOK_SECRET", "Env var containing the secret of the webhooks, the webhooks are disabled if not set")
+	}
+
+	return command, nil
+}
+
+const (
+	webhookPath           = "/webhook"
+	metricsPath           = "/metrics"
+	serverShutdownTimeout = 10 * time.Second
+)
+
+type serveOptions struct {
+	concurrency         int
+	defaultSchedule     schedule.Schedule
+	backoff, maxBackoff time.Duration
+	listen              string
+	webhookSecret       []byte
+}
+
+func getServeOptions(flagSet *pflag.FlagSet) (serveOptions, error) {
+	var opt serveOptions
+	var err error
+
+	if opt.concurrency, err = flagSet.GetInt(core.ConcurrencyFlag); err != nil {
+		return opt, err
+	}
+	if opt.concurrency < 1 {
+		return opt, fmt.Errorf("--%s must be at least 1", core.ConcurrencyFlag)
+	}
+	defaultSchedule, err := flagSet.GetString(core.DefaultScheduleFlag)
+	if err != nil {
+		return opt, err
+	}
+	if opt.defaultSchedule, err = schedule.Parse(defaultSchedule); err != nil {
+		return opt, fmt.Errorf("--%s: %w", core.DefaultScheduleFlag, err)
+	}
+	if opt.backoff, err = flagSet.GetDuration(core.BackoffFlag); err != nil {
+		return opt, err
+	}
+	if opt.maxBackoff, err = flagSet.GetDuration(core.MaxBackoffFlag); err != nil {
+		return opt, err
+	}
+	if opt.backoff <= 0 || opt.maxBackoff < opt.backoff {
+		return opt, fmt.Errorf("--%s must be positive and lower than --%s", core.BackoffFlag, core.MaxBackoffFlag)
+	}
+
+	if opt.listen, err = flagSet.GetString(core.ListenFlag); err != nil {
+		return opt, err
+	}
+	if opt.listen != "" {
+		secretEnv, err := flagSet.GetString(core.WebhookSecretEnvFlag)
+		if err != nil {
+			return opt, err
+		}
+		// The webhooks are never accepted unauthenticated.
+		if secret := os.Getenv(secretEnv); secret != "" {
+			opt.webhookSecret = []byte(secret)
+		} else {
+			log.Warn().Str(core.NameKey, secretEnv).Msg("webhook secret env var not set, webhooks disabled")
+		}
+	}
+
+	return opt, nil
+}
+
+// backoffDelay returns the delay before retrying an update after a number
+// of consecutive failures.
+func (o serveOptions) backoffDelay(failures int) time.Duration {
+	delay := o.backoff
+	for i := 1; i < failures && delay < o.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.maxBackoff {
+		return o.maxBackoff
+	}
+	return delay
+}
+
+// nextRun returns the time of the next update of the image, the updates
+// are retried with backoff until they succeed.
+func (o serveOptions) nextRun(opt CommonOptions, failures int, now time.Time) (time.Time, error) {
+	if failures > 0 {
+		return now.Add(o.backoffDelay(failures)), nil
+	}
+
+	build, err := core.NewBuildFromManifest(opt.buildFilePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sched := o.defaultSchedule
+	if build.Spec.Schedule != "" {
+		if sched, err = schedule.Parse(build.Spec.Schedule); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return sched.Next(now), nil
+}
+
+// serve updates each image on its schedule, or when a webhook reports a
+// release of one of its sources, until SIGINT or SIGTERM. The updates of
+// an image never overlap and the running updates are completed before
+// exiting.
+func serve(command *cobra.Command, args []string) error {
+	flagSet := command.Flags()
+
+	opt, err := getOptions(flagSet)
+	if err != nil {
+		return err
+	}
+	log.Info().Bool(core.EnabledKey, opt.dryRun).Msg(core.DryRunFlag)
+	if err := checkTestCmd(flagSet, command.Use); err != nil {
+		return err
+	}
+
+	serveOpt, err := getServeOptions(flagSet)
+	if err != nil {
+		return err
+	}
+
+	images, err := listImages(opt)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("no build file found in %s", opt.imagesDir)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A trigger received during an update runs another update once done.
+	triggers := map[string]chan struct{}{}
+	for _, image := range images {
+		triggers[image] = make(chan struct{}, 1)
+	}
+
+	var server *http.Server
+	if serveOpt.listen != "" {
+		listener, err := net.Listen("tcp", serveOpt.listen)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle(metricsPath, metrics.Default.Handler())
+		if serveOpt.webhookSecret != nil {
+			mux.Handle(webhookPath, webhook.NewHandler(serveOpt.webhookSecret, func(event *webhook.Event) []string {
+				return triggerImages(opt, images, triggers, event)
+			}))
+		}
+		server = &http.Server{Handler: mux}
+		go func() {
+			if err := server.Serve(listener); err != http.ErrServerClosed {
+				log.Error().AnErr(core.ErrKey, err).Msg("server failed")
+				stop()
+			}
+		}()
+		log.Info().Str(core.URLKey, listener.Addr().String()).Bool(core.EnabledKey, serveOpt.webhookSecret != nil).Msg("listen for metrics and webhooks")
+	}
+
+	sem := make(chan struct{}, serveOpt.concurrency)
+	var wg sync.WaitGroup
+	for _, image := range images {
+		wg.Add(1)
+		go func(imageOpt CommonOptions, trigger <-chan struct{}) {
+			defer wg.Done()
+			serveImage(ctx, flagSet, command.Use, imageOpt, serveOpt, sem, trigger)
+		}(opt.withImage(image), triggers[image])
+	}
+	log.Info().Int(core.CountKey, len(images)).Int(core.ConcurrencyFlag, serveOpt.concurrency).Msg("serve images")
+
+	<-ctx.Done()
+	log.Info().Msg("stop, wait for the running updates")
+	if server != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Warn().AnErr(core.ErrKey, err).Msg("server shutdown failed")
+		}
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// triggerImages triggers the update of the images with a source in the
+// repository of the event.
+func triggerImages(opt CommonOptions, images []string, triggers map[string]chan struct{}, event *webhook.Event) []string {
+	var triggered []string
+	for _, image := range images {
+		build, err := core.NewBuildFromManifest(opt.withImage(image).buildFilePath)
+		if err != nil {
+			log.Warn().Str(core.ImageKey, image).AnErr(core.ErrKey, err).Msg("read build file failed")
+			continue
+		}
+		for _, source := range build.Spec.Sources {
+			if !event.Matches(source) {
+				continue
+			}
+			select {
+			case triggers[image] <- struct{}{}:
+			default:
+			}
+			triggered = append(triggered, image)
+			break
+		}
+	}
+	return triggered
+}
+
+// listImages returns the image of the options or the images of the images
+// directory with a build file.
+func listImages(opt CommonOptions) ([]string, error) {
+	if opt.imageName != "" {
+		if _, err := os.Stat(opt.buildFilePath); err != nil {
+			return nil, err
+		}
+		return []string{opt.imageName}, nil
+	}
+
+	entries, err := ioutil.ReadDir(opt.imagesDir)
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(path.Join(opt.imagesDir, entry.Name(), opt.buildFileName)); err == nil {
+			images = append(images, entry.Name())
+		}
+	}
+	return images, nil
+}
+
+// serveImage runs the updates of an image on its schedule and on trigger,
+// sem limits the number of updates running at the same time.
+func serveImage(ctx context.Context, flagSet *pflag.FlagSet, mgrType string, opt CommonOptions,
+	serveOpt serveOptions, sem chan struct{}, trigger <-chan struct{}) {
+
+	logger := log.With().Str(core.ImageKey, opt.imageName).Logger()
+	failures := 0
+	for {
+		next, err := serveOpt.nextRun(opt, failures, time.Now())
+		if err != nil {
+			failures++
+			next = time.Now().Add(serveOpt.backoffDelay(failures))
+			logger.Error().AnErr(core.ErrKey, err).Time(core.NextKey, next).Msg("read schedule failed")
+		} else {
+			logger.Info().Time(core.NextKey, next).Msg("schedule update")
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-trigger:
+			timer.Stop()
+			logger.Info().Msg("update triggered by webhook")
+		case <-timer.C:
+			if err != nil {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case sem <- struct{}{}:
+		}
+		err = runServeUpdate(flagSet, mgrType, opt, logger)
+		<-sem
+
+		if err != nil {
+			failures++
+			logger.Error().AnErr(core.ErrKey, err).Int(core.CountKey, failures).
+				Dur(core.BackoffKey, serveOpt.backoffDelay(failures)).Msg("update failed")
+			continue
+		}
+		failures = 0
+	}
+}
+
+// runServeUpdate runs the update of an image, a panic fails the update
+// instead of stopping the other images.
+func runServeUpdate(flagSet *pflag.FlagSet, mgrType string, opt CommonOptions, logger zerolog.Logger) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("update panicked: %v", r)
+		}
+	}()
+
+	logger.Info().Msg("update image")
+	return updateImage(flagSet, mgrType, opt, newUpdateReport(logger))
+}
@@ -0,0 +1,421 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/execute"
+	"github.com/spiarh/gojo/pkg/manager"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+// Phases of the update.
+const (
+	factsPhase    = "facts"
+	buildPhase    = "build"
+	testPhase     = "test"
+	pushPhase     = "push"
+	commitPhase   = "commit"
+	rollbackPhase = "rollback"
+)
+
+var updatePhases = []string{factsPhase, buildPhase, testPhase, pushPhase, commitPhase}
+
+// commitMu serializes the commits of the updates run concurrently.
+var commitMu sync.Mutex
+
+// Statuses of the phases.
+const (
+	phaseOK      = "ok"
+	phaseFailed  = "failed"
+	phaseSkipped = "skipped"
+)
+
+func Update() (*cobra.Command, error) {
+	var command = &cobra.Command{
+		Use:   "update",
+		Short: "Update the facts, build, test and push an image, then commit its build file",
+		Example: `gojo update podman --image haproxy --name gojo --email gojo@example.com
+gojo update buildah --image haproxy --name gojo --email gojo@example.com --test-cmd 'podman run --rm "$1" haproxy -v'`,
+		SilenceUsage:      true,
+		TraverseChildren:  true,
+		PersistentPreRunE: SetGlobalLogLevel,
+	}
+
+	if err := addUpdateCommands(command, update, true); err != nil {
+		return nil, err
+	}
+	for _, mgrCommand := range command.Commands() {
+		AddMetricsFlags(mgrCommand)
+	}
+
+	return command, nil
+}
+
+// addUpdateCommands adds a subcommand with the update flags per manager.
+func addUpdateCommands(command *cobra.Command, run func(*cobra.Command, []string) error, imageRequired bool) error {
+	mgrTypes := []string{
+		string(manager.BuildahType),
+		string(manager.BuildkitType),
+		string(manager.KanikoType),
+		string(manager.PodmanType),
+	}
+	for _, mgrType := range mgrTypes {
+		mgrCommand := &cobra.Command{
+			Use:          mgrType,
+			RunE:         run,
+			SilenceUsage: true,
+		}
+		if err := AddCommonPersistentFlags(mgrCommand, imageRequired); err != nil {
+			return err
+		}
+		if err := AddCommitFlags(mgrCommand); err != nil {
+			return err
+		}
+		mgrCommand.PersistentFlags().Bool(core.TagLatestFlag, false, "Tag the built image as latest")
+		mgrCommand.PersistentFlags().String(core.TestCmdFlag, "", "Shell command testing the built image before the push, the image is passed as $1, not supported by the managers pushing during the build")
+		if mgrType == string(manager.BuildkitType) {
+			AddBuildkitFlags(mgrCommand)
+		}
+		command.AddCommand(mgrCommand)
+	}
+	return nil
+}
+
+// updatePhaseResult is the result of a phase of the update.
+type updatePhaseResult struct {
+	phase    string
+	status   string
+	duration time.Duration
+	detail   string
+}
+
+// updateReport records the results of the phases of the update.
+type updateReport struct {
+	log     zerolog.Logger
+	results []*updatePhaseResult
+	start   time.Time
+}
+
+func newUpdateReport(logger zerolog.Logger) *updateReport {
+	return &updateReport{log: logger}
+}
+
+func (r *updateReport) begin() {
+	r.start = time.Now()
+}
+
+func (r *updateReport) add(phase, status, detail string) {
+	result := &updatePhaseResult{phase: phase, status: status, detail: detail}
+	if status != phaseSkipped {
+		result.duration = time.Since(r.start).Round(time.Millisecond)
+	}
+	r.results = append(r.results, result)
+	r.log.Info().Str(core.PhaseKey, phase).Str(core.StatusKey, status).Msg(detail)
+}
+
+// skipRemaining marks the phases without result as skipped.
+func (r *updateReport) skipRemaining(detail string) {
+	done := map[string]bool{}
+	for _, result := range r.results {
+		done[result.phase] = true
+	}
+	for _, phase := range updatePhases {
+		if !done[phase] {
+			r.add(phase, phaseSkipped, detail)
+		}
+	}
+}
+
+func (r *updateReport) print() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PHASE\tSTATUS\tDURATION\tDETAIL")
+	for _, result := range r.results {
+		duration := "-"
+		if result.status != phaseSkipped {
+			duration = result.duration.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.phase, result.status, duration, result.detail)
+	}
+	w.Flush()
+}
+
+func update(command *cobra.Command, args []string) error {
+	flagSet := command.Flags()
+
+	opt, err := getOptions(flagSet)
+	if err != nil {
+		return err
+	}
+	log.Info().Bool(core.EnabledKey, opt.dryRun).Msg(core.DryRunFlag)
+	if err := checkTestCmd(flagSet, command.Use); err != nil {
+		return err
+	}
+	defer writeMetricsFile(flagSet)
+
+	report := newUpdateReport(log.Logger)
+	defer report.print()
+
+	return updateImage(flagSet, command.Use, opt, report)
+}
+
+// updateImage runs the update of the image and restores the build file if
+// the update failed before the commit.
+func updateImage(flagSet *pflag.FlagSet, mgrType string, opt CommonOptions, report *updateReport) error {
+	info, err := os.Stat(opt.buildFilePath)
+	if err != nil {
+		return err
+	}
+	original, err := ioutil.ReadFile(opt.buildFilePath)
+	if err != nil {
+		return err
+	}
+
+	err = runUpdate(flagSet, mgrType, opt, report)
+	if err == nil {
+		return nil
+	}
+
+	if rollback, ok := err.(*rollbackError); ok {
+		err = rollback.err
+		report.begin()
+		if rbErr := util.WriteToFile(opt.buildFilePath, original, info.Mode()); rbErr != nil {
+			report.add(rollbackPhase, phaseFailed, rbErr.Error())
+			return fmt.Errorf("%w, rollback of the build file failed: %s", err, rbErr)
+		}
+		report.add(rollbackPhase, phaseOK, "build file restored")
+	}
+	return err
+}
+
+// rollbackError is returned by the phases run once the build file is
+// updated, the build file is restored.
+type rollbackError struct {
+	err error
+}
+
+func (e *rollbackError) Error() string {
+	return e.err.Error()
+}
+
+// runUpdate updates the facts of the build file and, if the facts changed,
+// builds, tests and pushes the image before committing the build file. The
+// managers without Push push the image during the build and are not
+// tested.
+func runUpdate(flagSet *pflag.FlagSet, mgrType string, opt CommonOptions, report *updateReport) error {
+	report.begin()
+	oldBuild, err := core.NewBuildFromManifest(opt.buildFilePath)
+	if err != nil {
+		report.add(factsPhase, phaseFailed, err.Error())
+		report.skipRemaining("facts failed")
+		return err
+	}
+	build, err := core.NewBuildFromManifest(opt.buildFilePath)
+	if err != nil {
+		report.add(factsPhase, phaseFailed, err.Error())
+		report.skipRemaining("facts failed")
+		return err
+	}
+	if err := updateFacts(flagSet, build); err != nil {
+		report.add(factsPhase, phaseFailed, err.Error())
+		report.skipRemaining("facts failed")
+		return err
+	}
+
+	changes := core.DiffFacts(oldBuild, build)
+	// The tag is not compared as it may contain the date.
+	setUpdateAvailable(opt.imageName, len(changes) != 0)
+	if len(changes) == 0 {
+		report.add(factsPhase, phaseOK, "facts unchanged")
+		report.skipRemaining("facts unchanged")
+		return nil
+	}
+	exists, branch, err := pullRequestBranchExists(flagSet, opt, build)
+	if err != nil {
+		report.add(factsPhase, phaseFailed, err.Error())
+		report.skipRemaining("facts failed")
+		return err
+	}
+	if exists {
+		report.add(factsPhase, phaseOK, fmt.Sprintf("%d facts changed, branch %s exists", len(changes), branch))
+		report.skipRemaining("pull request branch exists")
+		return nil
+	}
+	if !opt.dryRun {
+		if err := build.WriteToFile(build.Image.BuildfilePath); err != nil {
+			report.add(factsPhase, phaseFailed, err.Error())
+			report.skipRemaining("facts failed")
+			return &rollbackError{err: err}
+		}
+	}
+	report.add(factsPhase, phaseOK, fmt.Sprintf("%d facts changed, tag %s -> %s", len(changes), oldBuild.Image.Tag, build.Image.Tag))
+
+	// Build
+	report.begin()
+	build.Image.Containerfile = opt.containerFileName
+	if err := build.Validate(); err != nil {
+		report.add(buildPhase, phaseFailed, err.Error())
+		report.skipRemaining("build failed")
+		return &rollbackError{err: err}
+	}
+	mgr, pusher, err := newUpdateManager(flagSet, mgrType)
+	if err != nil {
+		report.add(buildPhase, phaseFailed, err.Error())
+		report.skipRemaining("build failed")
+		return &rollbackError{err: err}
+	}
+	if err := buildImage(mgr, mgrType, opt, build); err != nil {
+		report.add(buildPhase, phaseFailed, err.Error())
+		report.skipRemaining("build failed")
+		return &rollbackError{err: err}
+	}
+	report.add(buildPhase, phaseOK, build.Image.String())
+	if pusher == nil {
+		report.add(pushPhase, phaseOK, fmt.Sprintf("pushed during the %s build", mgrType))
+	}
+
+	// Test
+	if err := runUpdateTest(flagSet, opt, build, report); err != nil {
+		report.skipRemaining("test failed")
+		return &rollbackError{err: err}
+	}
+
+	// Push
+	if pusher != nil {
+		report.begin()
+		if err := pusher.Push(build.Image); err != nil {
+			report.add(pushPhase, phaseFailed, err.Error())
+			report.skipRemaining("push failed")
+			return &rollbackError{err: err}
+		}
+		report.add(pushPhase, phaseOK, build.Image.String())
+	}
+
+	// Commit
+	report.begin()
+	if opt.dryRun {
+		report.add(commitPhase, phaseSkipped, "dry run")
+		return nil
+	}
+	// The images share the worktree, the commits are serialized.
+	commitMu.Lock()
+	defer commitMu.Unlock()
+	head, err := util.GetGitHeadHash(opt.imageDir)
+	if err != nil {
+		report.add(commitPhase, phaseFailed, err.Error())
+		return &rollbackError{err: err}
+	}
+	if err := commitImage(flagSet, opt); err != nil {
+		report.add(commitPhase, phaseFailed, err.Error())
+		// The build file is kept once committed.
+		if newHead, headErr := util.GetGitHeadHash(opt.imageDir); headErr == nil && newHead == head {
+			if resetErr := unstageImageFiles(opt); resetErr != nil {
+				return &rollbackError{err: fmt.Errorf("%w, reset of the index failed: %s", err, resetErr)}
+			}
+			return &rollbackError{err: err}
+		}
+		return err
+	}
+	report.add(commitPhase, phaseOK, "build file committed")
+	setUpdateAvailable(opt.imageName, false)
+
+	return nil
+}
+
+// unstageImageFiles resets the index entries of the files committed with
+// the image to HEAD, the build file restored by the rollback is not left
+// staged.
+func unstageImageFiles(opt CommonOptions) error {
+	repo, err := util.OpenGitRepo(opt.imageDir)
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+
+	paths, err := getImageCommitPaths(status, wt.Filesystem.Root(), opt.imageDir, opt.buildFilePath, nil)
+	if err != nil {
+		return err
+	}
+	return util.GitResetIndexFiles(repo, paths)
+}
+
+// newUpdateManager returns the manager of the build, the pusher is nil if
+// the manager pushes the image during the build.
+func newUpdateManager(flagSet *pflag.FlagSet, mgrType string) (manager.Manager, manager.Pusher, error) {
+	mgr, err := manager.NewWithPush(flagSet, mgrType, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pusher, ok := mgr.(manager.Pusher); ok {
+		return mgr, pusher, nil
+	}
+
+	mgr, err = manager.NewWithPush(flagSet, mgrType, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mgr, nil, nil
+}
+
+// checkTestCmd fails if the test command is set for a manager pushing the
+// image during the build, the image would be pushed before being tested.
+func checkTestCmd(flagSet *pflag.FlagSet, mgrType string) error {
+	testCmd, err := flagSet.GetString(core.TestCmdFlag)
+	if err != nil || testCmd == "" {
+		return err
+	}
+	mgr, err := manager.NewWithPush(flagSet, mgrType, false)
+	if err != nil {
+		return err
+	}
+	if _, ok := mgr.(manager.Pusher); !ok {
+		return fmt.Errorf("--%s can't be used with %s, the image is pushed during the build", core.TestCmdFlag, mgrType)
+	}
+	return nil
+}
+
+// runUpdateTest runs the test command with the image as first argument.
+func runUpdateTest(flagSet *pflag.FlagSet, opt CommonOptions, build *core.Build, report *updateReport) error {
+	report.begin()
+	testCmd, err := flagSet.GetString(core.TestCmdFlag)
+	if err != nil {
+		report.add(testPhase, phaseFailed, err.Error())
+		return err
+	}
+	if testCmd == "" {
+		report.add(testPhase, phaseSkipped, "no test command")
+		return nil
+	}
+
+	task := execute.ExecTask{
+		Log:         report.log.With().Str(core.PhaseKey, testPhase).Logger(),
+		Command:     "sh",
+		Args:        []string{"-c", testCmd, "gojo", build.Image.String()},
+		Cwd:         build.Image.Context,
+		DryRun:      opt.dryRun,
+		StreamStdio: util.IsTTYAllocated(),
+	}
+	if _, err := task.Execute(); err != nil {
+		report.add(testPhase, phaseFailed, err.Error())
+		return err
+	}
+	report.add(testPhase, phaseOK, testCmd)
+	return nil
+}
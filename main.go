@@ -27,9 +27,12 @@ func main() {
 		SilenceUsage: true,
 	}
 
-	var cmdBuild, cmdCommit, cmdFacts, cmdScaffold, cmdVersion *cobra.Command
+	var cmdAudit, cmdBuild, cmdCommit, cmdFacts, cmdScaffold, cmdServe, cmdUpdate, cmdVersion *cobra.Command
 	var err error
 
+	if cmdAudit, err = cmd.Audit(); err != nil {
+		log.Fatal().AnErr("err", err).Msg("")
+	}
 	if cmdBuild, err = cmd.Build(); err != nil {
 		log.Fatal().AnErr("err", err).Msg("")
 	}
@@ -42,12 +45,21 @@ func main() {
 	if cmdScaffold, err = cmd.Scaffold(); err != nil {
 		log.Fatal().AnErr("err", err).Msg("")
 	}
+	if cmdServe, err = cmd.Serve(); err != nil {
+		log.Fatal().AnErr("err", err).Msg("")
+	}
+	if cmdUpdate, err = cmd.Update(); err != nil {
+		log.Fatal().AnErr("err", err).Msg("")
+	}
 	cmdVersion = cmd.Version()
 
+	rootCmd.AddCommand(cmdAudit)
 	rootCmd.AddCommand(cmdBuild)
 	rootCmd.AddCommand(cmdCommit)
 	rootCmd.AddCommand(cmdFacts)
 	rootCmd.AddCommand(cmdScaffold)
+	rootCmd.AddCommand(cmdServe)
+	rootCmd.AddCommand(cmdUpdate)
 	rootCmd.AddCommand(cmdVersion)
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -0,0 +1,223 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/spiarh/gojo/pkg/provider"
+)
+
+type Severity string
+
+// The secdb does not carry any severity, CVEs without a known severity are
+// ranked as critical so that they are never silently ignored.
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+	SeverityUnknown  Severity = "unknown"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+	SeverityUnknown:  4,
+}
+
+// ParseSeverity returns the severity matching s.
+func ParseSeverity(s string) (Severity, error) {
+	sev := Severity(strings.ToLower(s))
+	if _, ok := severityRanks[sev]; !ok {
+		return "", fmt.Errorf("invalid severity: %s", s)
+	}
+	return sev, nil
+}
+
+// AtLeast returns true if the severity is greater or equal to threshold.
+func (s Severity) AtLeast(threshold Severity) bool {
+	return severityRanks[s] >= severityRanks[threshold]
+}
+
+type Status string
+
+const (
+	// StatusFixed means the package version includes the fix.
+	StatusFixed Status = "fixed"
+	// StatusUnfixed means the fix is only available in a newer version.
+	StatusUnfixed Status = "unfixed"
+)
+
+// Package is an Alpine package to audit.
+type Package struct {
+	Name string
+	// Origin is the name of the source package, which is the
+	// name used in the secdb.
+	Origin  string
+	Version string
+	// From describes where the package comes from, e.g a fact name.
+	From string
+}
+
+type Finding struct {
+	Package  Package
+	CVE      string
+	FixedIn  string
+	Status   Status
+	Severity Severity
+}
+
+// Severities maps a CVE ID to its severity.
+type Severities map[string]Severity
+
+// NewSeveritiesFromFile returns the severities from a JSON file
+// mapping CVE IDs to a severity, e.g {"CVE-2021-3449": "high"}.
+func NewSeveritiesFromFile(path string) (Severities, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]string)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	severities := make(Severities)
+	for cve, s := range raw {
+		sev, err := ParseSeverity(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cve: %s", cve)
+		}
+		severities[cve] = sev
+	}
+	return severities, nil
+}
+
+func (s Severities) get(cve string) Severity {
+	if sev, ok := s[cve]; ok {
+		return sev
+	}
+	return SeverityUnknown
+}
+
+// Audit cross-references the packages with the secdbs and returns the
+// findings sorted by package name and CVE.
+func Audit(secdbs []*SecDB, pkgs []Package, severities Severities) []Finding {
+	secfixes := make(map[string]map[string][]string)
+	for _, secdb := range secdbs {
+		for _, p := range secdb.Packages {
+			if _, ok := secfixes[p.Pkg.Name]; !ok {
+				secfixes[p.Pkg.Name] = make(map[string][]string)
+			}
+			for version, cves := range p.Pkg.Secfixes {
+				secfixes[p.Pkg.Name][version] = append(secfixes[p.Pkg.Name][version], cves...)
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, pkg := range pkgs {
+		name := pkg.Origin
+		if name == "" {
+			name = pkg.Name
+		}
+
+		for fixedIn, entries := range secfixes[name] {
+			if fixedIn == secdbNotAffected {
+				continue
+			}
+
+			status := StatusFixed
+			if provider.CompareAlpineVersions(pkg.Version, fixedIn) < 0 {
+				status = StatusUnfixed
+			}
+
+			for _, entry := range entries {
+				// An entry can reference several IDs, e.g "CVE-2020-1234 XSA-123".
+				for _, cve := range strings.Fields(entry) {
+					findings = append(findings, Finding{
+						Package:  pkg,
+						CVE:      cve,
+						FixedIn:  fixedIn,
+						Status:   status,
+						Severity: severities.get(cve),
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Package.Name != findings[j].Package.Name {
+			return findings[i].Package.Name < findings[j].Package.Name
+		}
+		return findings[i].CVE < findings[j].CVE
+	})
+
+	return findings
+}
+
+// Unfixed returns the unfixed findings with a severity at least equal to threshold.
+func Unfixed(findings []Finding, threshold Severity) []Finding {
+	var unfixed []Finding
+	for _, f := range findings {
+		if f.Status == StatusUnfixed && f.Severity.AtLeast(threshold) {
+			unfixed = append(unfixed, f)
+		}
+	}
+	return unfixed
+}
+
+// ParseInstalled parses an apk installed database, e.g /lib/apk/db/installed.
+func ParseInstalled(data []byte) ([]Package, error) {
+	var pkgs []Package
+	var pkg Package
+
+	appendPkg := func() error {
+		if pkg.Name == "" && pkg.Version == "" {
+			return nil
+		}
+		if pkg.Name == "" || pkg.Version == "" {
+			return fmt.Errorf("incomplete installed package: name=%s, version=%s", pkg.Name, pkg.Version)
+		}
+		pkg.From = "installed"
+		pkgs = append(pkgs, pkg)
+		pkg = Package{}
+		return nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "P:"):
+			pkg.Name = strings.TrimPrefix(line, "P:")
+		case strings.HasPrefix(line, "V:"):
+			pkg.Version = strings.TrimPrefix(line, "V:")
+		case strings.HasPrefix(line, "o:"):
+			pkg.Origin = strings.TrimPrefix(line, "o:")
+		case line == "":
+			if err := appendPkg(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "Invalid input")
+	}
+	if err := appendPkg(); err != nil {
+		return nil, err
+	}
+
+	return pkgs, nil
+}
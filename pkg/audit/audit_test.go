@@ -0,0 +1,69 @@
+package audit_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/audit"
+)
+
+var _ = Describe("Audit", func() {
+	secdb, err := audit.DecodeSecDB([]byte(`{
+  "distroversion": "v3.13",
+  "reponame": "main",
+  "packages": [
+    {"pkg": {"name": "openssl", "secfixes": {
+      "1.1.1i-r0": ["CVE-2020-1971"],
+      "1.1.1j-r0": ["CVE-2021-23840 CVE-2021-23841"],
+      "0": ["CVE-2019-0000"]
+    }}},
+    {"pkg": {"name": "nginx", "secfixes": {"1.18.0-r1": ["CVE-2020-0001"]}}}
+  ]
+}`))
+
+	It("decodes the secdb", func() {
+		Expect(err).To(BeNil())
+		Expect(secdb.Packages).To(HaveLen(2))
+	})
+
+	It("reports fixed and unfixed CVEs", func() {
+		pkgs := []audit.Package{{Name: "libcrypto1.1", Origin: "openssl", Version: "1.1.1i-r0"}}
+		findings := audit.Audit([]*audit.SecDB{secdb}, pkgs, audit.Severities{"CVE-2021-23840": audit.SeverityHigh})
+
+		Expect(findings).To(HaveLen(3))
+		Expect(findings[0].CVE).To(Equal("CVE-2020-1971"))
+		Expect(findings[0].Status).To(Equal(audit.StatusFixed))
+		Expect(findings[1].CVE).To(Equal("CVE-2021-23840"))
+		Expect(findings[1].Status).To(Equal(audit.StatusUnfixed))
+		Expect(findings[1].Severity).To(Equal(audit.SeverityHigh))
+		Expect(findings[2].Severity).To(Equal(audit.SeverityUnknown))
+
+		Expect(audit.Unfixed(findings, audit.SeverityLow)).To(HaveLen(2))
+		Expect(audit.Unfixed(findings, audit.SeverityCritical)).To(HaveLen(1))
+	})
+
+	It("compares versions with the apk ordering", func() {
+		pkgs := []audit.Package{{Name: "nginx", Version: "1.18.0-r10"}}
+		findings := audit.Audit([]*audit.SecDB{secdb}, pkgs, nil)
+		Expect(findings).To(HaveLen(1))
+		Expect(findings[0].Status).To(Equal(audit.StatusFixed))
+	})
+
+	It("parses the installed database", func() {
+		pkgs, err := audit.ParseInstalled([]byte(`C:Q1sD4hrXyoke/jQxvcO/Q+s2TGty4=
+P:libcrypto1.1
+V:1.1.1i-r0
+A:x86_64
+o:openssl
+
+P:musl
+V:1.2.2-r0
+A:x86_64
+`))
+		Expect(err).To(BeNil())
+		Expect(pkgs).To(HaveLen(2))
+		Expect(pkgs[0].Origin).To(Equal("openssl"))
+		Expect(pkgs[1].Name).To(Equal("musl"))
+		Expect(pkgs[1].Version).To(Equal("1.2.2-r0"))
+	})
+})
@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+const (
+	// SecDBDefaultMirror is the default location of the Alpine secdb.
+	SecDBDefaultMirror = "https://secdb.alpinelinux.org"
+	// SecDBDefaultTTL is the default duration a cached secdb is considered fresh.
+	SecDBDefaultTTL = 24 * time.Hour
+
+	// secdbNotAffected is the secfixes key used for CVEs not affecting the package.
+	secdbNotAffected = "0"
+)
+
+// SecDB is the secfixes database of one Alpine repository,
+// e.g https://secdb.alpinelinux.org/v3.13/main.json
+type SecDB struct {
+	DistroVersion string          `json:"distroversion"`
+	RepoName      string          `json:"reponame"`
+	URLPrefix     string          `json:"urlprefix"`
+	APKURL        string          `json:"apkurl"`
+	Packages      []SecDBPackages `json:"packages"`
+}
+
+type SecDBPackages struct {
+	Pkg SecDBPackage `json:"pkg"`
+}
+
+type SecDBPackage struct {
+	Name string `json:"name"`
+	// Secfixes maps the version fixing the CVEs to the list of CVEs.
+	Secfixes map[string][]string `json:"secfixes"`
+}
+
+// SecDBLoader loads secdb files from local files, a cache directory or a mirror.
+type SecDBLoader struct {
+	Mirror   string
+	CacheDir string
+	TTL      time.Duration
+}
+
+// NewSecDBFromFile returns a new decoded SecDB from a local file.
+func NewSecDBFromFile(path string) (*SecDB, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeSecDB(data)
+}
+
+func DecodeSecDB(data []byte) (*SecDB, error) {
+	secdb := &SecDB{}
+	if err := json.Unmarshal(data, secdb); err != nil {
+		return nil, err
+	}
+	return secdb, nil
+}
+
+// DefaultSecDBCacheDir returns the default cache directory for secdb files.
+func DefaultSecDBCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "gojo", "secdb")
+}
+
+func (l *SecDBLoader) cachePath(versionId, repo string) string {
+	return filepath.Join(l.CacheDir, "v"+versionId, repo+".json")
+}
+
+func (l *SecDBLoader) buildURL(versionId, repo string) string {
+	// https://secdb.alpinelinux.org/v3.13/main.json
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(l.Mirror, "/"), path.Join("v"+versionId, repo+".json"))
+}
+
+// Load returns the secdb of a repository, from the cache if fresh enough,
+// or from the mirror otherwise. A stale cache is used when the mirror
+// can't be reached.
+func (l *SecDBLoader) Load(versionId, repo string) (*SecDB, error) {
+	cachePath := l.cachePath(versionId, repo)
+	logger := log.With().Str("versionId", versionId).Str("repo", repo).Logger()
+
+	fileInfo, statErr := os.Stat(cachePath)
+	if statErr == nil && time.Since(fileInfo.ModTime()) < l.TTL {
+		logger.Debug().Str("file", cachePath).Msg("use cached secdb")
+		return NewSecDBFromFile(cachePath)
+	}
+
+	data, err := l.download(versionId, repo)
+	if err != nil {
+		if statErr == nil {
+			logger.Warn().AnErr("err", err).Str("file", cachePath).
+				Msg("secdb download failed, use stale cache")
+			return NewSecDBFromFile(cachePath)
+		}
+		return nil, err
+	}
+
+	secdb, err := DecodeSecDB(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := util.MakeDir(filepath.Dir(cachePath), 0755); err != nil {
+		return nil, err
+	}
+	if err := util.WriteToFile(cachePath, data, 0644); err != nil {
+		return nil, err
+	}
+
+	return secdb, nil
+}
+
+func (l *SecDBLoader) download(versionId, repo string) ([]byte, error) {
+	u := l.buildURL(versionId, repo)
+	log.Info().Str("url", u).Msg("download secdb")
+
+	resp, err := http.DefaultClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error getting secdb file: %d", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/blang/semver/v4"
 
+	"github.com/spiarh/gojo/pkg/schedule"
 	"github.com/spiarh/gojo/pkg/util"
 )
 
@@ -96,6 +97,13 @@ func (b *Build) GetBuildArgs() map[string]string {
 				buildArgs[fact.Name] = fact.Value
 				continue
 			}
+			// The raw version is the value if not transformed.
+			if arg == fact.Name+RawBuildArgSuffix {
+				buildArgs[arg] = fact.Value
+				if fact.RawValue != "" {
+					buildArgs[arg] = fact.RawValue
+				}
+			}
 		}
 	}
 	fromImageArg := "FROM_IMAGE"
@@ -111,6 +119,21 @@ func (b *Build) GetBuildArgs() map[string]string {
 	return buildArgs
 }
 
+// SetFromImagesTags sets the tag of the from images following a fact.
+func (b *Build) SetFromImagesTags() error {
+	for i, fromImage := range b.Spec.FromImages {
+		if fromImage.TagFact == "" {
+			continue
+		}
+		tag, err := getFactValue(b.Spec.Facts, fromImage.TagFact)
+		if err != nil {
+			return err
+		}
+		b.Spec.FromImages[i].Tag = tag
+	}
+	return nil
+}
+
 func (b *Build) ValidatePreProcess() error {
 	err := util.EnsureStringSliceDuplicates(b.Spec.BuildArgs)
 	if err != nil {
@@ -130,14 +153,71 @@ func (b *Build) ValidatePreProcess() error {
 		if source.Alpine != nil {
 			numProviders++
 		}
+		if source.AlpineRelease != nil {
+			numProviders++
+		}
+		if source.Apt != nil {
+			numProviders++
+		}
+		if source.Rpm != nil {
+			numProviders++
+		}
+		if source.PyPI != nil {
+			numProviders++
+		}
+		if source.Npm != nil {
+			numProviders++
+		}
+		if source.Crates != nil {
+			numProviders++
+		}
+		if source.GoProxy != nil {
+			numProviders++
+		}
+		if source.Helm != nil {
+			numProviders++
+		}
+		if source.HTTP != nil {
+			numProviders++
+			if source.HTTP.NumExtractors() > 1 {
+				return fmt.Errorf("only one extractor can be specified for http source: %s", source.Name)
+			}
+		}
+		if source.Git != nil {
+			numProviders++
+		}
 		if source.GitHub != nil {
 			numProviders++
 		}
+		if source.Transform != nil && source.Transform.Regex == "" {
+			return fmt.Errorf("transform regex missing for source: %s", source.Name)
+		}
+		if source.Alpine != nil && source.Alpine.VersionIdFact != "" && !hasFact(b.Spec.Facts, source.Alpine.VersionIdFact) {
+			return fmt.Errorf("versionIdFact not found: %s", source.Alpine.VersionIdFact)
+		}
 	}
 	if numProviders != len(b.Spec.Sources) {
 		return fmt.Errorf("too many providers specified for one source")
 	}
 
+	for _, pattern := range b.Spec.CommitFiles {
+		if err := util.ValidateRelGlob(pattern); err != nil {
+			return fmt.Errorf("invalid commitFiles pattern: %w", err)
+		}
+	}
+
+	if b.Spec.Schedule != "" {
+		if _, err := schedule.Parse(b.Spec.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+
+	for _, fromImage := range b.Spec.FromImages {
+		if fromImage.TagFact != "" && !hasFact(b.Spec.Facts, fromImage.TagFact) {
+			return fmt.Errorf("tagFact not found: %s", fromImage.TagFact)
+		}
+	}
+
 	for _, fact := range b.Spec.Facts {
 		if fact.Name == "" {
 			return fmt.Errorf("empty fact name")
@@ -158,13 +238,23 @@ func (b *Build) ValidatePreProcess() error {
 		if !found {
 			return fmt.Errorf("Source not found: %s", fact.Source)
 		}
-		if fact.Kind != VersionFactKind && fact.Semver != "" {
+		if !fact.Kind.HasVersionRange() && fact.Semver != "" {
 			return fmt.Errorf("SemVer specified for non version fact kind")
 		}
-		if fact.Kind == VersionFactKind && fact.Semver != "" {
-			if _, err := semver.ParseRange(fact.Semver); err != nil {
-				return err
+		if fact.Kind.IsAsset() && fact.Asset == "" {
+			return fmt.Errorf("asset missing for asset fact: %s", fact.Name)
+		}
+		switch fact.VersionScheme {
+		case "", SemverVersionScheme:
+			if fact.Semver != "" {
+				if _, err := semver.ParseRange(fact.Semver); err != nil {
+					return err
+				}
 			}
+		case APKVersionScheme, DebVersionScheme, RPMVersionScheme:
+			// ranges of other schemes are validated by the providers.
+		default:
+			return fmt.Errorf("unknown version scheme: %s", fact.VersionScheme)
 		}
 	}
 
@@ -7,8 +7,18 @@ const (
 	GitHubObjectTag     GitHubObject = "tag"
 )
 
+type HelmChartField string
+
+const (
+	HelmChartVersion    HelmChartField = "version"
+	HelmChartAppVersion HelmChartField = "appVersion"
+)
+
 const (
 	TagFormatVersion = "{{ .VERSION }}"
+	// RawBuildArgSuffix is appended to the name of a fact to pass its raw
+	// version as build arg, e.g VERSION_RAW.
+	RawBuildArgSuffix = "_RAW"
 )
 
 // Flags
@@ -39,12 +49,55 @@ const (
 
 	PushFlag      = "push"
 	TagLatestFlag = "tag-latest"
+	TestCmdFlag   = "test-cmd"
+
+	NameFlag            = "name"
+	EmailFlag           = "email"
+	MessageTemplateFlag = "message-template"
+
+	AllFlag         = "all"
+	ExtraFileFlag   = "extra-file"
+	PushRetriesFlag = "push-retries"
+
+	SignKeyFlag              = "sign-key"
+	SignFormatFlag           = "sign-format"
+	SignKeyPassphraseEnvFlag = "sign-key-passphrase-env"
+	TagFlag                  = "tag"
+
+	NoPushFlag                = "no-push"
+	RemoteFlag                = "remote"
+	RefSpecFlag               = "refspec"
+	SSHKeyFlag                = "ssh-key"
+	SSHKeyPassphraseEnvFlag   = "ssh-key-passphrase-env"
+	KnownHostsFlag            = "known-hosts"
+	InsecureIgnoreHostKeyFlag = "insecure-ignore-host-key"
+	GitUsernameFlag           = "git-username"
+	GitTokenEnvFlag           = "git-token-env"
+
+	PullRequestFlag         = "pull-request"
+	PullRequestProviderFlag = "pull-request-provider"
+	PullRequestAPIURLFlag   = "pull-request-api-url"
+
+	SecDBFileFlag    = "secdb-file"
+	SecDBMirrorFlag  = "secdb-mirror"
+	CacheDirFlag     = "cache-dir"
+	InstalledFlag    = "installed"
+	SeverityFileFlag = "severity-file"
+	FailOnFlag       = "fail-on"
+
+	ConcurrencyFlag     = "concurrency"
+	DefaultScheduleFlag = "default-schedule"
+	BackoffFlag         = "backoff"
+	MaxBackoffFlag      = "max-backoff"
 
-	NameFlag  = "name"
-	EmailFlag = "email"
+	ListenFlag           = "listen"
+	WebhookSecretEnvFlag = "webhook-secret-env"
+	MetricsFileFlag      = "metrics-file"
 )
 const (
 	DefaultLogLevel = "info"
+	// DateFormat is the format of the date facts.
+	DateFormat = "20060102150405"
 )
 
 // Filenames
@@ -63,6 +116,18 @@ const (
 	MsgKey     = "message"
 	HashKey    = "hash"
 	CommitKey  = "commit"
+	BranchKey  = "branch"
+	RemoteKey  = "remote"
+	RefSpecKey = "refspec"
+	AuthKey    = "auth"
+	TagKey     = "tag"
+	ImageKey   = "image"
+	CountKey   = "count"
+	PhaseKey   = "phase"
+	StatusKey  = "status"
+	URLKey     = "url"
+	NextKey    = "next"
+	BackoffKey = "backoff"
 	VersionKey = "VERSION"
 )
 
@@ -1,5 +1,10 @@
 package core
 
+import (
+	"fmt"
+	"strings"
+)
+
 func NewFact(name, value, source string, kind FactKind) *Fact {
 	return &Fact{
 		Name:   name,
@@ -9,3 +14,91 @@ func NewFact(name, value, source string, kind FactKind) *Fact {
 	}
 
 }
+
+// HasVersionRange returns true if the value of the fact kind is
+// derived from a version selected with a semver range.
+func (k FactKind) HasVersionRange() bool {
+	switch k {
+	case VersionFactKind, OriginFactKind, ChecksumFactKind, BuildDateFactKind, VersionIdFactKind,
+		AssetURLFactKind, AssetSHA256FactKind:
+		return true
+	}
+	return false
+}
+
+// getFactValue returns the value of the fact with name, an error is
+// returned if the fact does not exist or has no value yet.
+func getFactValue(facts []*Fact, name string) (string, error) {
+	for _, f := range facts {
+		if f.Name != name {
+			continue
+		}
+		if f.Value == "" {
+			return "", fmt.Errorf("fact referenced before having a value: %s", name)
+		}
+		return f.Value, nil
+	}
+	return "", fmt.Errorf("fact not found: %s", name)
+}
+
+// SortFactsByReferences returns the facts in resolution order, the facts
+// referenced by a source come before the facts of the source. The facts
+// without source keep their value and are not waited for.
+func SortFactsByReferences(facts []*Fact, sources []Source) ([]*Fact, error) {
+	references := map[string][]string{}
+	for i := range sources {
+		references[sources[i].Name] = sources[i].FactReferences()
+	}
+
+	resolved := map[string]bool{}
+	for _, f := range facts {
+		if f.Source == "" {
+			resolved[f.Name] = true
+		}
+	}
+
+	sorted := make([]*Fact, 0, len(facts))
+	remaining := facts
+	for len(remaining) > 0 {
+		var next []*Fact
+		for _, f := range remaining {
+			ready := true
+			for _, name := range references[f.Source] {
+				if !resolved[name] && hasFact(facts, name) {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				next = append(next, f)
+				continue
+			}
+			sorted = append(sorted, f)
+			resolved[f.Name] = true
+		}
+
+		if len(next) == len(remaining) {
+			var names []string
+			for _, f := range next {
+				names = append(names, f.Name)
+			}
+			return nil, fmt.Errorf("circular fact references: %s", strings.Join(names, ", "))
+		}
+		remaining = next
+	}
+	return sorted, nil
+}
+
+// IsAsset returns true if the fact kind is derived from a release asset.
+func (k FactKind) IsAsset() bool {
+	return k == AssetURLFactKind || k == AssetSHA256FactKind
+}
+
+func hasFact(facts []*Fact, name string) bool {
+	for _, f := range facts {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
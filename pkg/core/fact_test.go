@@ -0,0 +1,58 @@
+package core_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var _ = Describe("fact references", func() {
+	sources := []core.Source{
+		{Name: "alpine", Provider: core.Provider{AlpineRelease: &core.AlpineReleaseSource{}}},
+		{Name: "haproxy", Provider: core.Provider{Alpine: &core.AlpineSource{Package: "haproxy", VersionIdFact: "alpine-version-id"}}},
+	}
+
+	It("should resolve the referenced facts first", func() {
+		// The referenced fact is declared after the fact following it
+		// and has an outdated value.
+		facts := []*core.Fact{
+			core.NewFact("haproxy-version", "2.2.1", "haproxy", core.VersionFactKind),
+			core.NewFact("distro", "alpine", "", core.StringFactKind),
+			core.NewFact("alpine-version-id", "3.12", "alpine", core.VersionIdFactKind),
+			core.NewFact("alpine-version", "3.12.0", "alpine", core.VersionFactKind),
+		}
+
+		sorted, err := core.SortFactsByReferences(facts, sources)
+		Expect(err).To(BeNil())
+		var names []string
+		for _, f := range sorted {
+			names = append(names, f.Name)
+		}
+		Expect(names).To(Equal([]string{"distro", "alpine-version-id", "alpine-version", "haproxy-version"}))
+	})
+
+	It("should only follow the resolved facts", func() {
+		source := sources[1]
+		facts := []*core.Fact{
+			core.NewFact("alpine-version-id", "3.12", "alpine", core.VersionIdFactKind),
+		}
+		Expect(source.SetFactReferences(nil)).NotTo(Succeed())
+		Expect(source.SetFactReferences(facts)).To(Succeed())
+		Expect(source.Alpine.VersionId).To(Equal("3.12"))
+	})
+
+	It("should fail on circular references", func() {
+		circular := []core.Source{
+			{Name: "a", Provider: core.Provider{Alpine: &core.AlpineSource{Package: "a", VersionIdFact: "b-version"}}},
+			{Name: "b", Provider: core.Provider{Alpine: &core.AlpineSource{Package: "b", VersionIdFact: "a-version"}}},
+		}
+		facts := []*core.Fact{
+			core.NewFact("a-version", "", "a", core.VersionFactKind),
+			core.NewFact("b-version", "", "b", core.VersionFactKind),
+		}
+
+		_, err := core.SortFactsByReferences(facts, circular)
+		Expect(err).To(MatchError("circular fact references: a-version, b-version"))
+	})
+})
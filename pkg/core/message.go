@@ -0,0 +1,122 @@
+package core
+
+import (
+	"bytes"
+	"text/template"
+)
+
+// DefaultCommitMessageTemplate is the template of the commit messages
+// of the build files.
+const DefaultCommitMessageTemplate = `[gojo] New build file, image={{ .New.Image.Name }}, tag={{ .New.Image.Tag }}
+{{- if .Changes }}
+{{ range .Changes }}
+- {{ .Name }}: {{ or .Old "none" }} -> {{ .New }}{{ if .Source }} (source: {{ .Source }}){{ end }}
+{{- if .CompareURL }}
+  Compare: {{ .CompareURL }}
+{{- end }}
+{{- end }}
+{{- range .Changes }}
+{{- if .ReleaseNotes }}
+
+Release notes of {{ .Name }} {{ .New }}:
+
+{{ .ReleaseNotes }}
+{{- end }}
+{{- end }}
+{{- end }}
+`
+
+// DefaultBulkCommitMessageTemplate is the template of the commit messages
+// of the build files of several images.
+const DefaultBulkCommitMessageTemplate = `[gojo] New build files, images={{ len .Images }}
+{{ range .Images }}
+- {{ .New.Image.Name }}: {{ if .Old }}{{ .Old.Image.Tag }}{{ else }}none{{ end }} -> {{ .New.Image.Tag }}
+{{- range .Changes }}
+  - {{ .Name }}: {{ or .Old "none" }} -> {{ .New }}
+{{- end }}
+{{- end }}
+`
+
+// CommitMessageData is the data of the commit message templates.
+type CommitMessageData struct {
+	// Old is the committed build file, nil for a new build file.
+	Old *Build
+	New *Build
+	// Changes are the facts with a new value.
+	Changes []*FactChange
+}
+
+// BulkCommitMessageData is the data of the commit message templates of
+// several images.
+type BulkCommitMessageData struct {
+	Images []*CommitMessageData
+}
+
+// FactChange is the old and new value of a fact.
+type FactChange struct {
+	Name   string
+	Source string
+	Kind   FactKind
+	Old    string
+	New    string
+	// OldRaw and NewRaw are the versions as published upstream.
+	OldRaw string
+	NewRaw string
+
+	// ReleaseNotes and CompareURL are set for the sources publishing
+	// them, e.g GitHub releases.
+	ReleaseNotes string
+	CompareURL   string
+}
+
+// DiffFacts returns the facts of the new build file whose value changed.
+func DiffFacts(old, new *Build) []*FactChange {
+	oldFacts := map[string]*Fact{}
+	if old != nil && old.Spec != nil {
+		for _, f := range old.Spec.Facts {
+			oldFacts[f.Name] = f
+		}
+	}
+
+	var changes []*FactChange
+	for _, f := range new.Spec.Facts {
+		change := &FactChange{
+			Name:   f.Name,
+			Source: f.Source,
+			Kind:   f.Kind,
+			New:    f.Value,
+			NewRaw: rawValue(f),
+		}
+		if o, ok := oldFacts[f.Name]; ok {
+			if o.Value == f.Value {
+				continue
+			}
+			change.Old = o.Value
+			change.OldRaw = rawValue(o)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func rawValue(f *Fact) string {
+	if f.RawValue != "" {
+		return f.RawValue
+	}
+	return f.Value
+}
+
+// NewCommitMessage renders the commit message template, the data is a
+// CommitMessageData or a BulkCommitMessageData.
+func NewCommitMessage(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("CommitMessage").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var msg bytes.Buffer
+	if err := tmpl.Execute(&msg, data); err != nil {
+		return "", err
+	}
+	return msg.String(), nil
+}
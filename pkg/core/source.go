@@ -26,3 +26,54 @@ func NewGitHubSource(name, repo, owner string) Source {
 	}
 
 }
+
+// GetRepositories returns all the repositories in priority order.
+func (a *AlpineSource) GetRepositories() []string {
+	var repos []string
+	if a.Repository != "" {
+		repos = append(repos, a.Repository)
+	}
+	return append(repos, a.Repositories...)
+}
+
+// GetArchs returns all the archs, the first one is the main arch.
+func (a *AlpineSource) GetArchs() []string {
+	var archs []string
+	if a.Arch != "" {
+		archs = append(archs, a.Arch)
+	}
+	return append(archs, a.Archs...)
+}
+
+// NumExtractors returns the number of extractors defined.
+func (h *HTTPSource) NumExtractors() int {
+	n := 0
+	for _, e := range []string{h.JSONPath, h.XPath, h.Regex} {
+		if e != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// FactReferences returns the names of the facts the source follows.
+func (s *Source) FactReferences() []string {
+	var names []string
+	if s.Alpine != nil && s.Alpine.VersionIdFact != "" {
+		names = append(names, s.Alpine.VersionIdFact)
+	}
+	return names
+}
+
+// SetFactReferences sets the fields of the source following the value
+// of a fact, the facts referenced must already be resolved.
+func (s *Source) SetFactReferences(facts []*Fact) error {
+	if s.Alpine != nil && s.Alpine.VersionIdFact != "" {
+		value, err := getFactValue(facts, s.Alpine.VersionIdFact)
+		if err != nil {
+			return err
+		}
+		s.Alpine.VersionId = value
+	}
+	return nil
+}
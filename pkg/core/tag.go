@@ -15,7 +15,7 @@ func BuildTag(facts []*Fact, tagFormat, imageDir string) (string, error) {
 	}
 
 	// Date
-	now := time.Now().Format("20060102150405")
+	now := time.Now().Format(DateFormat)
 	factsMap["date"] = now
 
 	// Git
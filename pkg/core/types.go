@@ -27,28 +27,64 @@ type ImageSpec struct {
 	TagFormat  string      `yaml:"tagFormat,omitempty"`
 	Facts      []*Fact     `yaml:"facts,omitempty"`
 	Sources    []Source    `yaml:"sources,omitempty"`
+	// CommitFiles are the glob patterns of the files of the image
+	// directory committed with the build file, e.g Containerfile or
+	// *.lock.
+	CommitFiles []string `yaml:"commitFiles,omitempty"`
+	// Schedule is the interval (6h) or the cron expression (0 */6 * * *)
+	// of the updates run by gojo serve.
+	Schedule string `yaml:"schedule,omitempty"`
 }
 
 type FromImage struct {
 	Image  `yaml:",inline"`
 	Target string `yaml:"target,omitempty"`
+	// TagFact is the name of the fact the tag follows.
+	TagFact string `yaml:"tagFact,omitempty"`
 }
 
 type BuildArgs []string
 
 type Fact struct {
-	Name   string   `yaml:"name"`
-	Value  string   `yaml:"value"`
-	Source string   `yaml:"source,omitempty"`
-	Kind   FactKind `yaml:"kind"`
-	Semver string   `yaml:"semver,omitempty"`
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+	// RawValue is the version as published upstream when it differs
+	// from Value once transformed, e.g release-1.2.3.
+	RawValue string   `yaml:"rawValue,omitempty"`
+	Source   string   `yaml:"source,omitempty"`
+	Kind     FactKind `yaml:"kind"`
+	Semver   string   `yaml:"semver,omitempty"`
+	// VersionScheme is the scheme used to compare the versions
+	// with the semver range, semver by default.
+	VersionScheme VersionScheme `yaml:"versionScheme,omitempty"`
+	// Asset is the name of the release asset of the asset facts, the URL
+	// for the http sources, a template of the version,
+	// e.g app_{{ .Version }}_linux_amd64.tar.gz.
+	Asset string `yaml:"asset,omitempty"`
 }
 
 type FactKind string
 
 const (
-	VersionFactKind FactKind = "version"
-	StringFactKind  FactKind = "string"
+	VersionFactKind   FactKind = "version"
+	StringFactKind    FactKind = "string"
+	OriginFactKind    FactKind = "origin"
+	ChecksumFactKind  FactKind = "checksum"
+	BuildDateFactKind FactKind = "buildDate"
+	VersionIdFactKind FactKind = "versionId"
+	CommitFactKind    FactKind = "commit"
+	// The asset facts are the download URL and the SHA256 of an asset.
+	AssetURLFactKind    FactKind = "assetURL"
+	AssetSHA256FactKind FactKind = "assetSHA256"
+)
+
+type VersionScheme string
+
+const (
+	SemverVersionScheme VersionScheme = "semver"
+	APKVersionScheme    VersionScheme = "apk"
+	DebVersionScheme    VersionScheme = "deb"
+	RPMVersionScheme    VersionScheme = "rpm"
 )
 
 type FactInternalName string
@@ -59,25 +95,166 @@ const (
 )
 
 type Source struct {
-	Name     string `yaml:"name"`
-	Provider `yaml:",inline"`
+	Name string `yaml:"name"`
+	// Include and Exclude are regexes matched against the raw versions.
+	Include string `yaml:"include,omitempty"`
+	Exclude string `yaml:"exclude,omitempty"`
+	// Transform rewrites the raw versions to canonical versions.
+	Transform *Transform `yaml:"transform,omitempty"`
+	Provider  `yaml:",inline"`
+}
+
+// Transform is a regex replace, e.g ^release-(.*)$ replaced with $1.
+type Transform struct {
+	Regex   string `yaml:"regex"`
+	Replace string `yaml:"replace"`
 }
 
 type Provider struct {
-	Alpine *AlpineSource `yaml:"alpine,omitempty"`
-	GitHub *GitHubSource `yaml:"github,omitempty"`
+	Alpine        *AlpineSource        `yaml:"alpine,omitempty"`
+	AlpineRelease *AlpineReleaseSource `yaml:"alpineRelease,omitempty"`
+	Apt           *AptSource           `yaml:"apt,omitempty"`
+	Rpm           *RpmSource           `yaml:"rpm,omitempty"`
+	PyPI          *PyPISource          `yaml:"pypi,omitempty"`
+	Npm           *NpmSource           `yaml:"npm,omitempty"`
+	Crates        *CratesSource        `yaml:"crates,omitempty"`
+	GoProxy       *GoProxySource       `yaml:"goProxy,omitempty"`
+	Helm          *HelmSource          `yaml:"helm,omitempty"`
+	HTTP          *HTTPSource          `yaml:"http,omitempty"`
+	Git           *GitSource           `yaml:"git,omitempty"`
+	GitHub        *GitHubSource        `yaml:"github,omitempty"`
 }
 
 type AlpineSource struct {
 	Package    string `yaml:"package"`
+	Repository string `yaml:"repository,omitempty"`
+	// Repositories are searched in priority order, after Repository.
+	Repositories []string `yaml:"repositories,omitempty"`
+	VersionId    string   `yaml:"versionId"`
+	// VersionIdFact is the name of the fact VersionId follows.
+	VersionIdFact string `yaml:"versionIdFact,omitempty"`
+	Arch          string `yaml:"arch,omitempty"`
+	// Archs are the additional archs the version must be available for.
+	Archs  []string `yaml:"archs,omitempty"`
+	Mirror string   `yaml:"mirror,omitempty"`
+}
+
+type AlpineReleaseSource struct {
+	// Branch is the release branch, e.g v3.13, latest-stable by default.
+	Branch string `yaml:"branch,omitempty"`
+	// Flavor is the release flavor, alpine-minirootfs by default.
+	Flavor string `yaml:"flavor,omitempty"`
+	Arch   string `yaml:"arch,omitempty"`
+	Mirror string `yaml:"mirror,omitempty"`
+}
+
+type AptSource struct {
+	Package string `yaml:"package"`
+	// Suite is the distribution suite, e.g bullseye or focal-updates.
+	Suite string `yaml:"suite"`
+	// Component is the archive area, main by default.
+	Component string `yaml:"component,omitempty"`
+	Arch      string `yaml:"arch,omitempty"`
+	Mirror    string `yaml:"mirror,omitempty"`
+}
+
+type RpmSource struct {
+	Package string `yaml:"package"`
+	// BaseURL is the URL of the repository containing repodata/,
+	// e.g https://cdn-ubi.redhat.com/content/public/ubi/dist/ubi8/8/x86_64/baseos/os
+	BaseURL string `yaml:"baseURL"`
+	// Arch is x86_64 by default, noarch packages always match.
+	Arch string `yaml:"arch,omitempty"`
+}
+
+type PyPISource struct {
+	Project string `yaml:"project"`
+	// Index is the URL of the JSON API, https://pypi.org/pypi by default.
+	Index string `yaml:"index,omitempty"`
+}
+
+type NpmSource struct {
+	// Package is the package name, including the scope, e.g @babel/core.
+	Package  string `yaml:"package"`
+	Registry string `yaml:"registry,omitempty"`
+}
+
+type CratesSource struct {
+	Crate string `yaml:"crate"`
+	// API is the URL of the API, https://crates.io/api/v1 by default.
+	API string `yaml:"api,omitempty"`
+}
+
+type GoProxySource struct {
+	Module string `yaml:"module"`
+	// Proxy is the module proxy, https://proxy.golang.org by default.
+	Proxy string `yaml:"proxy,omitempty"`
+}
+
+type HelmSource struct {
+	// Repository is the URL of the chart repository serving index.yaml.
 	Repository string `yaml:"repository"`
-	VersionId  string `yaml:"versionId"`
-	Arch       string `yaml:"arch,omitempty"`
-	Mirror     string `yaml:"mirror,omitempty"`
+	Chart      string `yaml:"chart"`
+	// Field is the field of the chart returned, version by default.
+	Field HelmChartField `yaml:"field,omitempty"`
+}
+
+type HTTPSource struct {
+	URL string `yaml:"url"`
+	// Headers are added to the request, the environment variables in
+	// the values are expanded, e.g "Bearer ${API_TOKEN}".
+	Headers   map[string]string `yaml:"headers,omitempty"`
+	BasicAuth *HTTPBasicAuth    `yaml:"basicAuth,omitempty"`
+	// One extractor at most, the whole document is the value otherwise.
+	JSONPath string `yaml:"jsonPath,omitempty"`
+	XPath    string `yaml:"xpath,omitempty"`
+	// Regex returns the first capture group of each match.
+	Regex string `yaml:"regex,omitempty"`
+	// Checksums is the URL of the checksums file of the assets, a template
+	// like the asset of the facts, the assets are downloaded otherwise.
+	Checksums string `yaml:"checksums,omitempty"`
+}
+
+// HTTPBasicAuth holds the names of the environment variables containing
+// the credentials.
+type HTTPBasicAuth struct {
+	UsernameEnv string `yaml:"usernameEnv"`
+	PasswordEnv string `yaml:"passwordEnv"`
+}
+
+type GitSource struct {
+	URL string `yaml:"url"`
+	// Branches adds the branches to the tags as candidate versions.
+	Branches bool `yaml:"branches,omitempty"`
+	// Branch is the branch of the commit facts, the remote HEAD by default.
+	Branch string `yaml:"branch,omitempty"`
 }
 
 type GitHubSource struct {
 	Owner      string       `yaml:"owner"`
 	Repository string       `yaml:"repository"`
 	Object     GitHubObject `yaml:"object"`
+	// Limit is the maximum number of releases or tags retrieved, the most
+	// recent first, 1000 by default.
+	Limit int `yaml:"limit,omitempty"`
+	// BaseURL is the API URL of a GitHub Enterprise server,
+	// e.g https://github.example.com/api/v3/
+	BaseURL string `yaml:"baseURL,omitempty"`
+	// TokenEnv is the env var containing the token, GITHUB_TOKEN by default.
+	TokenEnv string `yaml:"tokenEnv,omitempty"`
+	// App authenticates as a GitHub App installation.
+	App *GitHubAppAuth `yaml:"app,omitempty"`
+	// Netrc reads the credentials of the API host from $NETRC or ~/.netrc.
+	Netrc bool `yaml:"netrc,omitempty"`
+	// Checksums is the name of the checksums asset of the releases, a
+	// template like the asset of the facts, e.g checksums.txt. The assets
+	// are downloaded otherwise.
+	Checksums string `yaml:"checksums,omitempty"`
+}
+
+type GitHubAppAuth struct {
+	AppID int64 `yaml:"appId"`
+	// InstallationID is the installation of the repository by default.
+	InstallationID int64  `yaml:"installationId,omitempty"`
+	PrivateKeyFile string `yaml:"privateKeyFile"`
 }
@@ -10,17 +10,32 @@ import (
 	"github.com/spiarh/gojo/pkg/util"
 )
 
-type manager interface {
+// Manager builds the images.
+type Manager interface {
 	Build(ibc *core.Build) error
 }
 
-var _ manager = &Podman{}
+// Pusher is implemented by the managers pushing the images in a step
+// separate from the build.
+type Pusher interface {
+	Push(image *core.Image) error
+}
+
+var _ Manager = &Podman{}
+var _ Pusher = &Podman{}
+var _ Pusher = &Buildah{}
 
-func New(flagSet *pflag.FlagSet, mgrType string) (manager, error) {
+func New(flagSet *pflag.FlagSet, mgrType string) (Manager, error) {
 	push, err := flagSet.GetBool(core.PushFlag)
 	if err != nil {
 		return nil, err
 	}
+	return NewWithPush(flagSet, mgrType, push)
+}
+
+// NewWithPush returns a manager pushing the images after the build, or
+// not, regardless of the --push flag.
+func NewWithPush(flagSet *pflag.FlagSet, mgrType string, push bool) (Manager, error) {
 	tagLatest, err := flagSet.GetBool(core.TagLatestFlag)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,44 @@
+package metrics
+
+// Default is the registry of the metrics of gojo.
+var Default = NewRegistry()
+
+// Labels of the metrics.
+const (
+	ImageLabel    = "image"
+	ProviderLabel = "provider"
+	ManagerLabel  = "manager"
+	StatusLabel   = "status"
+	HostLabel     = "host"
+)
+
+// Statuses of the builds.
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+var (
+	// ImageLastSuccess is the time of the last successful build per image.
+	ImageLastSuccess = NewGaugeVec(Default, "gojo_image_last_success_timestamp_seconds",
+		"Unix time of the last successful build of the image.", ImageLabel)
+	// ImageUpdateAvailable is 1 while the facts of an image changed
+	// upstream and the update isn't committed.
+	ImageUpdateAvailable = NewGaugeVec(Default, "gojo_image_update_available",
+		"Whether the facts of the image changed upstream and the update is not committed.", ImageLabel)
+
+	FactDuration = NewHistogramVec(Default, "gojo_fact_resolution_duration_seconds",
+		"Duration of the resolution of the facts per provider.",
+		[]float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}, ProviderLabel)
+	FactErrors = NewCounterVec(Default, "gojo_fact_resolution_errors_total",
+		"Number of failed resolutions of facts per provider.", ProviderLabel)
+
+	BuildDuration = NewHistogramVec(Default, "gojo_build_duration_seconds",
+		"Duration of the builds per manager.",
+		[]float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600}, ManagerLabel)
+	Builds = NewCounterVec(Default, "gojo_builds_total",
+		"Number of builds per manager and status.", ManagerLabel, StatusLabel)
+
+	GitHubRateLimitRemaining = NewGaugeVec(Default, "gojo_github_rate_limit_remaining",
+		"Number of requests remaining in the rate limit of the GitHub API.", HostLabel)
+)
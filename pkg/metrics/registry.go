@@ -0,0 +1,245 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	counterType   = "counter"
+	gaugeType     = "gauge"
+	histogramType = "histogram"
+
+	// contentType is the text exposition format of Prometheus.
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// Registry holds the metrics exposed together.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*vec
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(v *vec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, v)
+}
+
+// Write writes the metrics in the text exposition format of Prometheus,
+// the metrics without series are omitted.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]*vec(nil), r.metrics...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, v := range metrics {
+		v.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns the handler serving the metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		if err := r.Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// WriteFile writes the metrics to a file read by the textfile collector of
+// the node exporter, the file is replaced atomically.
+func (r *Registry) WriteFile(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := r.Write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// vec is a metric with a series per label values.
+type vec struct {
+	name, help, typ string
+	labels          []string
+	// buckets are the upper bounds of the histogram buckets.
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// counts are the cumulative counts of the histogram buckets.
+	counts []uint64
+	count  uint64
+}
+
+func newVec(r *Registry, name, help, typ string, buckets []float64, labels []string) *vec {
+	v := &vec{name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: map[string]*series{}}
+	r.register(v)
+	return v
+}
+
+// get returns the series of the label values, it panics if the number of
+// label values doesn't match the labels.
+func (v *vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s: %d label values for %d labels", v.name, len(labelValues), len(v.labels)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...), counts: make([]uint64, len(v.buckets))}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *vec) write(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.series) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.typ)
+	for _, key := range keys {
+		s := v.series[key]
+		if v.typ != histogramType {
+			fmt.Fprintf(w, "%s%s %s\n", v.name, v.formatLabels(s.labelValues, ""), formatFloat(s.value))
+			continue
+		}
+		for i, bound := range v.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, v.formatLabels(s.labelValues, formatFloat(bound)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, v.formatLabels(s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, v.formatLabels(s.labelValues, ""), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, v.formatLabels(s.labelValues, ""), s.count)
+	}
+}
+
+// labelEscaper escapes the backslashes, the double quotes and the new lines
+// of the label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels returns the labels of a series, le is the bucket label of
+// the histograms.
+func (v *vec) formatLabels(labelValues []string, le string) string {
+	var pairs []string
+	for i, label := range v.labels {
+		pairs = append(pairs, label+`="`+labelEscaper.Replace(labelValues[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// CounterVec is a counter per label values.
+type CounterVec struct {
+	*vec
+}
+
+func NewCounterVec(r *Registry, name, help string, labels ...string) *CounterVec {
+	return &CounterVec{newVec(r, name, help, counterType, nil, labels)}
+}
+
+// Inc increments the counter of the label values.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(labelValues).value++
+}
+
+// GaugeVec is a gauge per label values.
+type GaugeVec struct {
+	*vec
+}
+
+func NewGaugeVec(r *Registry, name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{newVec(r, name, help, gaugeType, nil, labels)}
+}
+
+// Set sets the gauge of the label values.
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.get(labelValues).value = value
+}
+
+// HistogramVec is a histogram per label values.
+type HistogramVec struct {
+	*vec
+}
+
+// NewHistogramVec returns a histogram with buckets of the sorted upper
+// bounds, the +Inf bucket is implicit.
+func NewHistogramVec(r *Registry, name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{newVec(r, name, help, histogramType, buckets, labels)}
+}
+
+// Observe adds a value to the histogram of the label values.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(labelValues)
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
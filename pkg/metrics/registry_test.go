@@ -0,0 +1,95 @@
+package metrics_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/metrics"
+)
+
+var _ = Describe("metrics", func() {
+	var registry *metrics.Registry
+
+	BeforeEach(func() {
+		registry = metrics.NewRegistry()
+	})
+
+	It("should write the metrics in the text format", func() {
+		gauge := metrics.NewGaugeVec(registry, "gojo_gauge", "A gauge.", "image")
+		counter := metrics.NewCounterVec(registry, "gojo_counter_total", "A counter.", "manager", "status")
+		histogram := metrics.NewHistogramVec(registry, "gojo_duration_seconds", "A histogram.", []float64{1, 2.5}, "provider")
+		metrics.NewGaugeVec(registry, "gojo_empty", "No series.", "image")
+
+		gauge.Set(1, "haproxy")
+		gauge.Set(1614556800, "alpine")
+		counter.Inc("podman", "success")
+		counter.Inc("podman", "success")
+		counter.Inc("podman", "failure")
+		histogram.Observe(0.5, "github")
+		histogram.Observe(2, "github")
+		histogram.Observe(3, "github")
+
+		var buf bytes.Buffer
+		Expect(registry.Write(&buf)).To(Succeed())
+		Expect(buf.String()).To(Equal(`# HELP gojo_gauge A gauge.
+# TYPE gojo_gauge gauge
+gojo_gauge{image="alpine"} 1.6145568e+09
+gojo_gauge{image="haproxy"} 1
+# HELP gojo_counter_total A counter.
+# TYPE gojo_counter_total counter
+gojo_counter_total{manager="podman",status="failure"} 1
+gojo_counter_total{manager="podman",status="success"} 2
+# HELP gojo_duration_seconds A histogram.
+# TYPE gojo_duration_seconds histogram
+gojo_duration_seconds_bucket{provider="github",le="1"} 1
+gojo_duration_seconds_bucket{provider="github",le="2.5"} 2
+gojo_duration_seconds_bucket{provider="github",le="+Inf"} 3
+gojo_duration_seconds_sum{provider="github"} 5.5
+gojo_duration_seconds_count{provider="github"} 3
+`))
+	})
+
+	It("should escape the label values", func() {
+		gauge := metrics.NewGaugeVec(registry, "gojo_gauge", "A gauge.", "image")
+		gauge.Set(1, "a\"b\\c\nd\u00e9")
+
+		var buf bytes.Buffer
+		Expect(registry.Write(&buf)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring(`gojo_gauge{image="a\"b\\c\ndé"} 1`))
+	})
+
+	It("should panic on wrong label values", func() {
+		gauge := metrics.NewGaugeVec(registry, "gojo_gauge", "A gauge.", "image")
+		Expect(func() { gauge.Set(1) }).To(Panic())
+	})
+
+	It("should serve and write the metrics", func() {
+		metrics.NewCounterVec(registry, "gojo_counter_total", "A counter.", "manager").Inc("kaniko")
+
+		rec := httptest.NewRecorder()
+		registry.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+		Expect(rec.Body.String()).To(ContainSubstring(`gojo_counter_total{manager="kaniko"} 1`))
+
+		dir, err := ioutil.TempDir("", "gojo")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "gojo.prom")
+		Expect(registry.WriteFile(path)).To(Succeed())
+		data, err := ioutil.ReadFile(path)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal(rec.Body.String()))
+		entries, err := ioutil.ReadDir(dir)
+		Expect(err).To(BeNil())
+		Expect(entries).To(HaveLen(1))
+	})
+})
@@ -9,13 +9,16 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/blang/semver/v4"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/spiarh/gojo/pkg/core"
 	"github.com/spiarh/gojo/pkg/util"
 )
 
@@ -28,35 +31,48 @@ const (
 
 type Alpine struct {
 	log zerolog.Logger
+	filtered
 
-	arch      string
-	mirror    string
-	repo      string
+	// archs are the architectures the package must be available for,
+	// the first one is used to select the version.
+	archs  []string
+	mirror string
+	// repos are searched in priority order.
+	repos     []string
 	versionId string
 	pkgName   string
+
+	// indexes caches the parsed APKINDEX per repository and arch.
+	indexes map[alpineIndexKey][]*AlpinePackageMeta
+}
+
+type alpineIndexKey struct {
+	repo string
+	arch string
 }
 
-func NewAlpine(mirror, arch, versionId, repo, pkgName string) *Alpine {
+func NewAlpine(mirror string, archs []string, versionId string, repos []string, pkgName string) *Alpine {
 	return &Alpine{
 		log:       log.With().Str("provider", string(ProviderAlpine)).Logger(),
 		mirror:    mirror,
-		arch:      arch,
+		archs:     archs,
 		versionId: versionId,
-		repo:      repo,
+		repos:     repos,
 		pkgName:   pkgName,
+		indexes:   make(map[alpineIndexKey][]*AlpinePackageMeta),
 	}
 }
 
-func (a *Alpine) buildURL() (*url.URL, error) {
+func (a *Alpine) buildURL(repo, arch string) (*url.URL, error) {
 	// http://dl-cdn.alpinelinux.org/alpine/v3.13/main/x86_64/APKINDEX.tar.gz
 	v := "v" + a.versionId
-	path := path.Join(alpineOS, v, a.repo, a.arch, alpineAPKIndexArchiveName)
+	path := path.Join(alpineOS, v, repo, arch, alpineAPKIndexArchiveName)
 	u := fmt.Sprintf("%s/%s", a.mirror, path)
 	return url.Parse(u)
 }
 
-func (a *Alpine) getAPKIndexArchive() (io.ReadCloser, error) {
-	apkIndexURL, err := a.buildURL()
+func (a *Alpine) getAPKIndexArchive(repo, arch string) (io.ReadCloser, error) {
+	apkIndexURL, err := a.buildURL(repo, arch)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +84,8 @@ func (a *Alpine) getAPKIndexArchive() (io.ReadCloser, error) {
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("error getting apk index file: %d", resp.StatusCode)
+		resp.Body.Close()
+		return nil, fmt.Errorf("error getting apk index file: %d, url=%s", resp.StatusCode, apkIndexURL)
 	}
 
 	return resp.Body, nil
@@ -89,49 +106,149 @@ func (a *Alpine) getAPKIndexFromArchive(archive io.ReadCloser) ([]byte, error) {
 	return apkIndex, nil
 }
 
-func (a *Alpine) GetLatest(semverRange string) (string, error) {
-	apkIndexArchive, err := a.getAPKIndexArchive()
+func (a *Alpine) getPackages(repo, arch string) ([]*AlpinePackageMeta, error) {
+	key := alpineIndexKey{repo: repo, arch: arch}
+	if pkgs, ok := a.indexes[key]; ok {
+		return pkgs, nil
+	}
+
+	apkIndexArchive, err := a.getAPKIndexArchive(repo, arch)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer apkIndexArchive.Close()
 
 	apkIndex, err := a.getAPKIndexFromArchive(apkIndexArchive)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	pkg, err := parseAPKIndex(apkIndex, a.pkgName)
+	pkgs, err := parseAPKIndex(apkIndex)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	a.indexes[key] = pkgs
+
+	return pkgs, nil
+}
+
+// getLatestPackage returns the package with the highest version satisfying
+// the range from the first repository providing it. The version must be
+// available for all the archs.
+func (a *Alpine) getLatestPackage(scheme core.VersionScheme, semverRange string) (*AlpinePackageMeta, error) {
+	if len(a.archs) == 0 || len(a.repos) == 0 {
+		return nil, fmt.Errorf("no arch or repository defined for package: %s", a.pkgName)
 	}
-	version := pkg.version
+	arch := a.archs[0]
 
-	if semverRange != "" {
-		expectedRange, err := semver.ParseRange(semverRange)
+	var pkg *AlpinePackageMeta
+	var repo string
+	var errs []string
+	for _, repo = range a.repos {
+		pkgs, err := a.getPackages(repo, arch)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		v, err := semver.Parse(version)
-		if err != nil {
-			return "", err
+
+		candidates := findAPKPackages(pkgs, a.pkgName)
+		if len(candidates) == 0 {
+			a.log.Debug().Str("repo", repo).Str("arch", arch).
+				Str("package", a.pkgName).
+				Msg("package not found in repository")
+			continue
 		}
-		if !expectedRange(v) {
-			return "", fmt.Errorf("no version found matching semver, version=%s, semver='%s'", version, semverRange)
+
+		if pkg, err = selectLatestAPKPackage(candidates, a.filter, scheme, semverRange); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", repo, err))
+			continue
 		}
+		break
+	}
+	if pkg == nil {
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
+		return nil, fmt.Errorf("Package not found: %s", a.pkgName)
 	}
 
-	a.log.Info().Str("version", version).
+	if err := a.ensureAvailableForArchs(pkg.version); err != nil {
+		return nil, err
+	}
+
+	a.log.Info().Str("version", pkg.version).
 		Str("semver", semverRange).
+		Str("repo", repo).
+		Str("arch", strings.Join(a.archs, ",")).
 		Msg("version found")
 
-	return version, nil
+	return pkg, nil
+}
+
+// ensureAvailableForArchs returns an error if the version of the package
+// is not available in any of the repositories for one of the archs.
+func (a *Alpine) ensureAvailableForArchs(version string) error {
+	for _, arch := range a.archs[1:] {
+		found := false
+		for _, repo := range a.repos {
+			pkgs, err := a.getPackages(repo, arch)
+			if err != nil {
+				return err
+			}
+			for _, pkg := range findAPKPackages(pkgs, a.pkgName) {
+				if CompareAlpineVersions(pkg.version, version) == 0 {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("version not available for arch, package=%s, version=%s, arch=%s", a.pkgName, version, arch)
+		}
+	}
+	return nil
+}
+
+func (a *Alpine) GetLatest(semverRange string) (string, error) {
+	pkg, err := a.getLatestPackage(core.SemverVersionScheme, semverRange)
+	if err != nil {
+		return "", err
+	}
+	return pkg.version, nil
+}
+
+func (a *Alpine) GetFact(fact *core.Fact) (string, error) {
+	pkg, err := a.getLatestPackage(fact.VersionScheme, fact.Semver)
+	if err != nil {
+		return "", err
+	}
+
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		return pkg.version, nil
+	case core.OriginFactKind:
+		return pkg.origin, nil
+	case core.ChecksumFactKind:
+		return pkg.checksum, nil
+	case core.BuildDateFactKind:
+		return pkg.buildTime.UTC().Format(core.DateFormat), nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderAlpine, fact.Kind)
 }
 
 type AlpinePackageMeta struct {
-	name    string
-	version string
-	arch    string
+	name         string
+	version      string
+	arch         string
+	checksum     string
+	size         int64
+	dependencies []string
+	provides     []string
+	origin       string
+	buildTime    time.Time
 }
 
 func (a *AlpinePackageMeta) isValid() error {
@@ -148,39 +265,124 @@ func (a *AlpinePackageMeta) isValid() error {
 	return nil
 }
 
-func parseAPKIndex(apkIndex []byte, pkgName string) (*AlpinePackageMeta, error) {
+// findAPKPackages returns all the packages named pkgName.
+func findAPKPackages(pkgs []*AlpinePackageMeta, pkgName string) []*AlpinePackageMeta {
+	var found []*AlpinePackageMeta
+	for _, pkg := range pkgs {
+		if pkg.name == pkgName {
+			found = append(found, pkg)
+		}
+	}
+	return found
+}
+
+// selectLatestAPKPackage returns the package with the highest version
+// selected by the filter and satisfying the range, using the apk version
+// ordering.
+func selectLatestAPKPackage(pkgs []*AlpinePackageMeta, filter *VersionFilter, scheme core.VersionScheme, semverRange string) (*AlpinePackageMeta, error) {
+	sorted := make([]*AlpinePackageMeta, len(pkgs))
+	copy(sorted, pkgs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return CompareAlpineVersions(sorted[i].version, sorted[j].version) > 0
+	})
+
+	expectedRange, err := parseVersionRange(scheme, semverRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, pkg := range sorted {
+		if !filter.Match(pkg.version) {
+			continue
+		}
+		versions = append(versions, pkg.version)
+		ok, err := expectedRange(filter.Transform(pkg.version))
+		if err != nil {
+			log.Warn().Str("version", pkg.version).
+				Err(err).
+				Msg("parsing version failed")
+			continue
+		}
+		if ok {
+			return pkg, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no version found matching semver, versions=%s, semver='%s'", strings.Join(versions, ","), semverRange)
+}
+
+// parseAPKIndex parses all the packages of an APKINDEX.
+func parseAPKIndex(apkIndex []byte) ([]*AlpinePackageMeta, error) {
 	sc := bufio.NewScanner(bytes.NewReader(apkIndex))
-	a := AlpinePackageMeta{}
+	// Some lines (e.g dependencies) exceed the default buffer size.
+	sc.Buffer(make([]byte, bufio.MaxScanTokenSize), 1024*1024)
+
+	var pkgs []*AlpinePackageMeta
+	a := &AlpinePackageMeta{}
+
+	endBlock := func() error {
+		if a.name == "" && a.version == "" && a.arch == "" {
+			return nil
+		}
+		if err := a.isValid(); err != nil {
+			return err
+		}
+		pkgs = append(pkgs, a)
+		// Start the new block with an empty struct
+		a = &AlpinePackageMeta{}
+		return nil
+	}
 
-Loop:
 	for sc.Scan() {
 		line := sc.Text()
-		switch {
-		case strings.HasPrefix(line, "P:"):
-			a.name = strings.Split(line, ":")[1]
-		case strings.HasPrefix(line, "V:"):
-			a.version = strings.Split(line, ":")[1]
-		case strings.HasPrefix(line, "A:"):
-			a.arch = strings.Split(line, ":")[1]
-		case line == "":
-			if a.name == pkgName {
-				break Loop
+		if line == "" {
+			if err := endBlock(); err != nil {
+				return nil, err
 			}
-			// Start the new block with an empty struct
-			a = AlpinePackageMeta{}
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+
+		value := line[2:]
+		switch line[0] {
+		case 'P':
+			a.name = value
+		case 'V':
+			a.version = value
+		case 'A':
+			a.arch = value
+		case 'C':
+			a.checksum = value
+		case 'S':
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid size for package: %s", a.name)
+			}
+			a.size = size
+		case 'D':
+			a.dependencies = strings.Fields(value)
+		case 'p':
+			a.provides = strings.Fields(value)
+		case 'o':
+			a.origin = value
+		case 't':
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid build time for package: %s", a.name)
+			}
+			a.buildTime = time.Unix(ts, 0)
 		}
 	}
 
 	if err := sc.Err(); err != nil {
 		return nil, errors.Wrap(err, "Invalid input")
 	}
-
-	if a.name == pkgName {
-		if err := a.isValid(); err != nil {
-			return nil, err
-		}
-		return &a, nil
+	if err := endBlock(); err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("Package not found: %s", pkgName)
+	return pkgs, nil
 }
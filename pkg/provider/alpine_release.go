@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+const (
+	alpineReleasesFilename      = "latest-releases.yaml"
+	alpineReleaseDefaultBranch  = "latest-stable"
+	alpineReleaseDefaultFlavor  = "alpine-minirootfs"
+	alpineReleasesDirectoryName = "releases"
+)
+
+type AlpineRelease struct {
+	log zerolog.Logger
+	filtered
+
+	mirror string
+	branch string
+	arch   string
+	flavor string
+
+	// releases caches the parsed releases file.
+	releases []alpineReleaseMeta
+}
+
+// alpineReleaseMeta is an entry of latest-releases.yaml.
+type alpineReleaseMeta struct {
+	Title   string `yaml:"title"`
+	Branch  string `yaml:"branch"`
+	Arch    string `yaml:"arch"`
+	Version string `yaml:"version"`
+	Flavor  string `yaml:"flavor"`
+	File    string `yaml:"file"`
+	Date    string `yaml:"date"`
+	Sha256  string `yaml:"sha256"`
+}
+
+func NewAlpineRelease(mirror, branch, arch, flavor string) *AlpineRelease {
+	return &AlpineRelease{
+		log:    log.With().Str("provider", string(ProviderAlpineRelease)).Logger(),
+		mirror: mirror,
+		branch: branch,
+		arch:   arch,
+		flavor: flavor,
+	}
+}
+
+func (a *AlpineRelease) buildURL() (*url.URL, error) {
+	// http://dl-cdn.alpinelinux.org/alpine/latest-stable/releases/x86_64/latest-releases.yaml
+	path := path.Join(alpineOS, a.branch, alpineReleasesDirectoryName, a.arch, alpineReleasesFilename)
+	u := fmt.Sprintf("%s/%s", a.mirror, path)
+	return url.Parse(u)
+}
+
+func (a *AlpineRelease) getReleases() ([]alpineReleaseMeta, error) {
+	if a.releases != nil {
+		return a.releases, nil
+	}
+
+	releasesURL, err := a.buildURL()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Get(releasesURL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error getting alpine releases file: %d, url=%s", resp.StatusCode, releasesURL)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.releases, err = parseAlpineReleases(data); err != nil {
+		return nil, err
+	}
+
+	return a.releases, nil
+}
+
+func parseAlpineReleases(data []byte) ([]alpineReleaseMeta, error) {
+	var releases []alpineReleaseMeta
+	if err := yaml.Unmarshal(data, &releases); err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// getRelease returns the release of the flavor with the highest version
+// selected by the filter and satisfying the semver range.
+func (a *AlpineRelease) getRelease(semverRange string) (*alpineReleaseMeta, error) {
+	releases, err := a.getReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	expectedRange, err := parseVersionRange(core.SemverVersionScheme, semverRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []alpineReleaseMeta
+	for _, r := range releases {
+		if r.Flavor == a.flavor {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no release found for flavor: %s", a.flavor)
+	}
+
+	parse := a.filter.parser(parseSanitizedSemver)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		vi, erri := parse(candidates[i].Version)
+		vj, errj := parse(candidates[j].Version)
+		if erri != nil || errj != nil {
+			return erri == nil
+		}
+		return vi.GT(vj)
+	})
+
+	var versions []string
+	for i, r := range candidates {
+		if !a.filter.Match(r.Version) {
+			continue
+		}
+		versions = append(versions, r.Version)
+
+		ok, err := expectedRange(a.filter.Transform(r.Version))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		a.log.Info().Str("version", r.Version).
+			Str("branch", r.Branch).
+			Str("flavor", r.Flavor).
+			Msg("release found")
+
+		return &candidates[i], nil
+	}
+
+	return nil, fmt.Errorf("no version found matching semver, versions=%s, semver='%s'", strings.Join(versions, ","), semverRange)
+}
+
+func (a *AlpineRelease) GetFact(fact *core.Fact) (string, error) {
+	r, err := a.getRelease(fact.Semver)
+	if err != nil {
+		return "", err
+	}
+
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		return r.Version, nil
+	case core.VersionIdFactKind:
+		return util.SanitizeVersion(r.Branch), nil
+	case core.ChecksumFactKind:
+		return r.Sha256, nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderAlpineRelease, fact.Kind)
+}
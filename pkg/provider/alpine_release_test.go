@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var _ = Describe("Alpine Release Provider", func() {
+	releases := `---
+-
+  title: "Standard"
+  branch: v3.13
+  arch: x86_64
+  version: 3.13.2
+  flavor: alpine-standard
+  file: alpine-standard-3.13.2-x86_64.iso
+  sha256: 2f9a1f4d4a8c8cda5aa58a2a1ec4b0df8aa0b2a5d2bbd3f9e4c1c8e7f1c0f9a1
+-
+  title: "Mini root filesystem"
+  branch: v3.13
+  arch: x86_64
+  version: 3.13.2
+  flavor: alpine-minirootfs
+  file: alpine-minirootfs-3.13.2-x86_64.tar.gz
+  sha256: 6a1a2cde9d8bc7c8f5ba8c01a7ba7b4a1e0e4cb7d8e5d4a2b2e4b1d2a8c3f1e0
+-
+  title: "Mini root filesystem"
+  branch: v3.12
+  arch: x86_64
+  version: 3.12.4
+  flavor: alpine-minirootfs
+  file: alpine-minirootfs-3.12.4-x86_64.tar.gz
+  sha256: 0c5b2bbbd9b8f0e3a2a5d7a0f1f6c9e4d3b2a1c0f9e8d7c6b5a4f3e2d1c0b9a8
+`
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/alpine/latest-stable/releases/x86_64/latest-releases.yaml" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(releases))
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("returns the release facts", func() {
+		a := NewAlpineRelease(server.URL, alpineReleaseDefaultBranch, "x86_64", alpineReleaseDefaultFlavor)
+
+		tests := []struct {
+			kind     core.FactKind
+			expected string
+		}{
+			{kind: core.VersionFactKind, expected: "3.13.2"},
+			{kind: core.VersionIdFactKind, expected: "3.13"},
+			{kind: core.ChecksumFactKind, expected: "6a1a2cde9d8bc7c8f5ba8c01a7ba7b4a1e0e4cb7d8e5d4a2b2e4b1d2a8c3f1e0"},
+		}
+		for _, tt := range tests {
+			value, err := a.GetFact(&core.Fact{Kind: tt.kind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal(tt.expected))
+		}
+	})
+
+	It("falls back to the next release when the filter excludes the newest", func() {
+		f, err := NewVersionFilter(core.Source{Exclude: `^3\.13\.`})
+		Expect(err).To(BeNil())
+
+		a := NewAlpineRelease(server.URL, alpineReleaseDefaultBranch, "x86_64", alpineReleaseDefaultFlavor)
+		a.setFilter(f)
+		value, err := a.GetFact(&core.Fact{Kind: core.VersionFactKind})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("3.12.4"))
+	})
+	It("fails when the release does not match the semver range", func() {
+		a := NewAlpineRelease(server.URL, alpineReleaseDefaultBranch, "x86_64", alpineReleaseDefaultFlavor)
+		_, err := a.GetFact(&core.Fact{Kind: core.VersionFactKind, Semver: "<3.12.0"})
+		Expect(err).To(HaveOccurred())
+	})
+})
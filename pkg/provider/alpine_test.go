@@ -1,8 +1,18 @@
 package provider
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
 )
 
 var _ = Describe("Alpine Provider", func() {
@@ -14,34 +24,51 @@ var _ = Describe("Alpine Provider", func() {
 
 		Context("with well formed APK Index", func() {
 			BeforeEach(func() {
-				apkIndex = []byte(`P:mariadb
+				apkIndex = []byte(`C:Q1sD4hrXyoke/jQxvcO/Q+s2TGty4=
+P:mariadb
 V:10.4.17-r1
 A:x86_64
 
+C:Q1A4SgQUwzEoR+ZyAgL6lbYAzXOtI=
 P:nginx
 V:1.18.0-r1
 A:x86_64
+S:433410
+o:nginx
+t:1603108962
+D:/bin/sh so:libc.musl-x86_64.so.1
+p:cmd:nginx
 
 `)
 			})
 			It("finds package meta", func() {
 				pkgName = "nginx"
-				expectedPkgMeta := &AlpinePackageMeta{name: "nginx", version: "1.18.0-r1", arch: "x86_64"}
-				actual, err := parseAPKIndex(apkIndex, pkgName)
+				expectedPkgMeta := &AlpinePackageMeta{
+					name:         "nginx",
+					version:      "1.18.0-r1",
+					arch:         "x86_64",
+					checksum:     "Q1A4SgQUwzEoR+ZyAgL6lbYAzXOtI=",
+					size:         433410,
+					dependencies: []string{"/bin/sh", "so:libc.musl-x86_64.so.1"},
+					provides:     []string{"cmd:nginx"},
+					origin:       "nginx",
+					buildTime:    time.Unix(1603108962, 0),
+				}
+				pkgs, err := parseAPKIndex(apkIndex)
 				Expect(err).To(BeNil())
-				Expect(actual).To(Equal(expectedPkgMeta))
+				Expect(pkgs).To(HaveLen(2))
+				actual := findAPKPackages(pkgs, pkgName)
+				Expect(actual).To(Equal([]*AlpinePackageMeta{expectedPkgMeta}))
 			})
 			It("fails to find package meta", func() {
 				pkgName = "missing"
-				actual, err := parseAPKIndex(apkIndex, pkgName)
-				Expect(err).To(HaveOccurred())
-				Expect(actual).To(BeNil())
+				pkgs, err := parseAPKIndex(apkIndex)
+				Expect(err).To(BeNil())
+				actual := findAPKPackages(pkgs, pkgName)
+				Expect(actual).To(BeEmpty())
 			})
 		})
 		Context("with malformed APK Index", func() {
-			BeforeEach(func() {
-				pkgName = "nginx"
-			})
 			It("fails because of incomplete data", func() {
 				apkIndex = []byte(`P:mariadb
 V:10.4.17-r1
@@ -51,7 +78,7 @@ P:nginx
 V:1.18.0-r1
 
 `)
-				actual, err := parseAPKIndex(apkIndex, pkgName)
+				actual, err := parseAPKIndex(apkIndex)
 				Expect(err).To(HaveOccurred())
 				Expect(actual).To(BeNil())
 			})
@@ -66,11 +93,128 @@ P:nginx
 A:x86_64
 
 `)
-				pkgName = "nginx"
-				actual, err := parseAPKIndex(apkIndex, pkgName)
+				actual, err := parseAPKIndex(apkIndex)
 				Expect(err).To(HaveOccurred())
 				Expect(actual).To(BeNil())
 			})
 		})
+		Context("with a full APK Index", func() {
+			It("parses all the packages", func() {
+				apkIndex, err := ioutil.ReadFile("testdata/APKINDEX")
+				Expect(err).To(BeNil())
+				pkgs, err := parseAPKIndex(apkIndex)
+				Expect(err).To(BeNil())
+				Expect(pkgs).To(HaveLen(4835))
+				Expect(findAPKPackages(pkgs, "nginx")[0].version).To(Equal("1.18.0-r1"))
+			})
+		})
+	})
+
+	Describe("Select latest package", func() {
+		pkgs := []*AlpinePackageMeta{
+			{name: "nginx", version: "1.18.0-r1"},
+			{name: "nginx", version: "1.19.6-r0"},
+			{name: "nginx", version: "1.18.0-r10"},
+			{name: "nginx", version: "1.16.1-r6"},
+		}
+
+		It("selects the highest version", func() {
+			pkg, err := selectLatestAPKPackage(pkgs, nil, "", "")
+			Expect(err).To(BeNil())
+			Expect(pkg.version).To(Equal("1.19.6-r0"))
+		})
+		It("selects the highest version satisfying the range", func() {
+			pkg, err := selectLatestAPKPackage(pkgs, nil, "", "<1.19.0")
+			Expect(err).To(BeNil())
+			Expect(pkg.version).To(Equal("1.18.0-r10"))
+		})
+		It("selects the highest version satisfying the apk range", func() {
+			pkg, err := selectLatestAPKPackage(pkgs, nil, core.APKVersionScheme, ">=1.18.0-r2 <1.19")
+			Expect(err).To(BeNil())
+			Expect(pkg.version).To(Equal("1.18.0-r10"))
+
+			pkg, err = selectLatestAPKPackage(pkgs, nil, core.APKVersionScheme, "~1.16")
+			Expect(err).To(BeNil())
+			Expect(pkg.version).To(Equal("1.16.1-r6"))
+		})
+		It("selects the highest version selected by the filter", func() {
+			f, err := NewVersionFilter(core.Source{
+				Exclude:   `^1\.19\.`,
+				Transform: &core.Transform{Regex: `^(.*)-r\d+$`, Replace: "$1"},
+			})
+			Expect(err).To(BeNil())
+
+			pkg, err := selectLatestAPKPackage(pkgs, f, "", ">=1.18.0")
+			Expect(err).To(BeNil())
+			Expect(pkg.version).To(Equal("1.18.0-r10"))
+		})
+		It("fails when no version satisfies the range", func() {
+			_, err := selectLatestAPKPackage(pkgs, nil, "", ">=2.0.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Multiple repositories and archs", func() {
+		var server *httptest.Server
+
+		// indexes maps the path of the APKINDEX archives to their content.
+		indexes := map[string]string{
+			"/alpine/v3.13/main/x86_64/APKINDEX.tar.gz":       "P:nginx\nV:1.18.0-r1\nA:x86_64\n\n",
+			"/alpine/v3.13/main/aarch64/APKINDEX.tar.gz":      "P:nginx\nV:1.18.0-r1\nA:aarch64\n\n",
+			"/alpine/v3.13/community/x86_64/APKINDEX.tar.gz":  "P:haproxy\nV:2.2.9-r0\nA:x86_64\n\nP:nginx\nV:1.19.6-r0\nA:x86_64\n\n",
+			"/alpine/v3.13/community/aarch64/APKINDEX.tar.gz": "P:nginx\nV:1.19.6-r0\nA:aarch64\n\n",
+		}
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				content, ok := indexes[r.URL.Path]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write(newAPKIndexArchive(content))
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("searches the repositories in priority order", func() {
+			a := NewAlpine(server.URL, []string{"x86_64"}, "3.13", []string{"main", "community"}, "nginx")
+			version, err := a.GetLatest("")
+			Expect(err).To(BeNil())
+			Expect(version).To(Equal("1.18.0-r1"))
+
+			a = NewAlpine(server.URL, []string{"x86_64"}, "3.13", []string{"main", "community"}, "haproxy")
+			version, err = a.GetLatest("")
+			Expect(err).To(BeNil())
+			Expect(version).To(Equal("2.2.9-r0"))
+		})
+		It("ensures the version is available for all the archs", func() {
+			a := NewAlpine(server.URL, []string{"x86_64", "aarch64"}, "3.13", []string{"community"}, "nginx")
+			version, err := a.GetLatest("")
+			Expect(err).To(BeNil())
+			Expect(version).To(Equal("1.19.6-r0"))
+
+			a = NewAlpine(server.URL, []string{"x86_64", "aarch64"}, "3.13", []string{"community"}, "haproxy")
+			_, err = a.GetLatest("")
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
+
+func newAPKIndexArchive(content string) []byte {
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+	_ = tarWriter.WriteHeader(&tar.Header{
+		Name:     alpineAPKIndexFilename,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	})
+	_, _ = tarWriter.Write([]byte(content))
+	_ = tarWriter.Close()
+	_ = gzWriter.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,204 @@
+package provider
+
+// Alpine version comparison following the apk-tools implementation
+// (src/version.c). Versions are split in tokens whose type depends on the
+// previous token, e.g 1.2.3_rc1-r0 is digit, digit, digit, suffix,
+// suffix number and revision number.
+
+type apkTokenType int
+
+const (
+	apkTokenInvalid apkTokenType = iota - 1
+	apkTokenDigitOrZero
+	apkTokenDigit
+	apkTokenLetter
+	apkTokenSuffix
+	apkTokenSuffixNo
+	apkTokenRevisionNo
+	apkTokenEnd
+)
+
+var (
+	apkPreSuffixes  = []string{"alpha", "beta", "pre", "rc"}
+	apkPostSuffixes = []string{"cvs", "svn", "git", "hg", "p"}
+)
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+func isLower(c byte) bool { return c >= 'a' && c <= 'z' }
+
+// nextAPKToken finds the type of the next token and consumes its separator.
+func nextAPKToken(t *apkTokenType, s *string) {
+	n := apkTokenInvalid
+	str := *s
+
+	switch {
+	case len(str) == 0:
+		n = apkTokenEnd
+	case (*t == apkTokenDigit || *t == apkTokenDigitOrZero) && isLower(str[0]):
+		n = apkTokenLetter
+	case *t == apkTokenLetter && isDigit(str[0]):
+		n = apkTokenDigit
+	case *t == apkTokenSuffix && isDigit(str[0]):
+		n = apkTokenSuffixNo
+	default:
+		switch str[0] {
+		case '.':
+			n = apkTokenDigitOrZero
+		case '_':
+			n = apkTokenSuffix
+		case '-':
+			if len(str) > 1 && str[1] == 'r' {
+				n = apkTokenRevisionNo
+				str = str[1:]
+			}
+		}
+		str = str[1:]
+	}
+
+	if n < *t {
+		if !((n == apkTokenDigitOrZero && *t == apkTokenDigit) ||
+			(n == apkTokenSuffix && *t == apkTokenSuffixNo) ||
+			(n == apkTokenDigit && *t == apkTokenLetter)) {
+			n = apkTokenInvalid
+		}
+	}
+
+	*t = n
+	*s = str
+}
+
+// getAPKToken returns the value of the current token and moves to the next one.
+func getAPKToken(t *apkTokenType, s *string) int {
+	str := *s
+	v, i := 0, 0
+	nt := apkTokenInvalid
+
+	if len(str) == 0 {
+		*t = apkTokenEnd
+		return 0
+	}
+
+	switch *t {
+	case apkTokenDigitOrZero, apkTokenDigit, apkTokenSuffixNo, apkTokenRevisionNo:
+		// Leading zero digits get a special treatment.
+		if *t == apkTokenDigitOrZero && str[0] == '0' {
+			for i < len(str) && str[i] == '0' {
+				i++
+			}
+			nt = apkTokenDigit
+			v = -i
+			break
+		}
+		for i < len(str) && isDigit(str[i]) {
+			v = v*10 + int(str[i]-'0')
+			i++
+		}
+	case apkTokenLetter:
+		v = int(str[i])
+		i++
+	case apkTokenSuffix:
+		found := false
+		for idx, suffix := range apkPreSuffixes {
+			if len(str) >= len(suffix) && str[:len(suffix)] == suffix {
+				v, i, found = idx-len(apkPreSuffixes), len(suffix), true
+				break
+			}
+		}
+		if !found {
+			for idx, suffix := range apkPostSuffixes {
+				if len(str) >= len(suffix) && str[:len(suffix)] == suffix {
+					v, i, found = idx, len(suffix), true
+					break
+				}
+			}
+		}
+		if !found {
+			*t = apkTokenInvalid
+			return -1
+		}
+	default:
+		*t = apkTokenInvalid
+		return -1
+	}
+
+	str = str[i:]
+	switch {
+	case len(str) == 0:
+		*t = apkTokenEnd
+	case nt != apkTokenInvalid:
+		*t = nt
+	default:
+		nextAPKToken(t, &str)
+	}
+	*s = str
+
+	return v
+}
+
+// CompareAlpineVersions compares two Alpine package versions using the apk
+// ordering. It returns -1 if a < b, 0 if a == b and 1 if a > b.
+func CompareAlpineVersions(a, b string) int {
+	return compareAlpineVersions(a, b, false)
+}
+
+// FuzzyMatchAlpineVersion returns true if the version starts with the
+// components of prefix, e.g 1.18.0-r1 matches 1.18 but 1.180 does not.
+func FuzzyMatchAlpineVersion(version, prefix string) bool {
+	return compareAlpineVersions(version, prefix, true) == 0
+}
+
+func compareAlpineVersions(a, b string, fuzzy bool) int {
+	at, bt := apkTokenDigit, apkTokenDigit
+	av, bv := 0, 0
+
+	for at == bt && at != apkTokenEnd && at != apkTokenInvalid && av == bv {
+		av = getAPKToken(&at, &a)
+		bv = getAPKToken(&bt, &b)
+	}
+
+	// value of this token differs?
+	if av < bv {
+		return -1
+	}
+	if av > bv {
+		return 1
+	}
+
+	// both have end or invalid next?
+	if at == bt {
+		return 0
+	}
+	// fuzzy matching of the prefix
+	if fuzzy && bt == apkTokenEnd {
+		return 0
+	}
+
+	// leading version components and their values are equal, now the
+	// non-terminating version is greater unless it's a suffix
+	// indicating a pre-release.
+	tt := at
+	if at == apkTokenSuffix && getAPKToken(&tt, &a) < 0 {
+		return -1
+	}
+	tt = bt
+	if bt == apkTokenSuffix && getAPKToken(&tt, &b) < 0 {
+		return 1
+	}
+	if at > bt {
+		return -1
+	}
+	if bt > at {
+		return 1
+	}
+
+	return 0
+}
+
+// ValidateAlpineVersion returns true if the version is a valid apk version.
+func ValidateAlpineVersion(v string) bool {
+	t := apkTokenDigit
+	for t != apkTokenEnd && t != apkTokenInvalid {
+		getAPKToken(&t, &v)
+	}
+	return t == apkTokenEnd
+}
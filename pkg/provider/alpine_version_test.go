@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alpine Version", func() {
+	It("compares versions like apk-tools", func() {
+		// testdata/version.data is test/version.data of apk-tools, the
+		// comments start with #.
+		f, err := os.Open("testdata/version.data")
+		Expect(err).To(BeNil())
+		defer f.Close()
+
+		results := map[string]int{"<": -1, "=": 0, ">": 1}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			fields := strings.Fields(strings.SplitN(sc.Text(), "#", 2)[0])
+			Expect(fields).To(HaveLen(3))
+			a, op, b := fields[0], fields[1], fields[2]
+
+			Expect(CompareAlpineVersions(a, b)).To(Equal(results[op]), "%s %s %s", a, op, b)
+			Expect(CompareAlpineVersions(b, a)).To(Equal(-results[op]), "%s %s %s", b, op, a)
+		}
+		Expect(sc.Err()).To(BeNil())
+	})
+
+	It("validates versions", func() {
+		for _, v := range []string{"1", "1.0", "1.2.3_rc1-r0", "2.4_rc2-r0", "1.2.3_p4-r2", "1.0a", "1.0_git20200101"} {
+			Expect(ValidateAlpineVersion(v)).To(BeTrue(), v)
+		}
+		for _, v := range []string{"1.0bc", "1.0_foo", "1.2-x", "1.2.3-r1a", "1.2_rc1_foo"} {
+			Expect(ValidateAlpineVersion(v)).To(BeFalse(), v)
+		}
+	})
+
+	It("matches fuzzy versions", func() {
+		Expect(FuzzyMatchAlpineVersion("1.18.0-r1", "1.18")).To(BeTrue())
+		Expect(FuzzyMatchAlpineVersion("1.18", "1.18")).To(BeTrue())
+		Expect(FuzzyMatchAlpineVersion("1.180.0-r1", "1.18")).To(BeFalse())
+		Expect(FuzzyMatchAlpineVersion("1.1", "1.18")).To(BeFalse())
+	})
+
+	It("parses ranges", func() {
+		r, err := ParseAlpineRange(">=1.18.0-r2 <1.19 || =1.20.1-r0")
+		Expect(err).To(BeNil())
+		Expect(r("1.18.0-r2")).To(BeTrue())
+		Expect(r("1.18.0-r10")).To(BeTrue())
+		Expect(r("1.18.0-r1")).To(BeFalse())
+		Expect(r("1.19.0-r0")).To(BeFalse())
+		Expect(r("1.20.1-r0")).To(BeTrue())
+
+		for _, invalid := range []string{"", ">=", ">=1.0 ||", "<1.0_foo"} {
+			_, err := ParseAlpineRange(invalid)
+			Expect(err).To(HaveOccurred(), invalid)
+		}
+	})
+})
@@ -0,0 +1,311 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/ulikunitz/xz"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+const (
+	aptPackagesFilename = "Packages"
+	aptDefaultMirror    = "http://deb.debian.org/debian"
+	aptDefaultComponent = "main"
+	aptDefaultArch      = "amd64"
+)
+
+// aptCompressions are the compressed Packages indexes tried in order.
+var aptCompressions = []string{".xz", ".gz"}
+
+type Apt struct {
+	log zerolog.Logger
+	filtered
+
+	mirror    string
+	suite     string
+	component string
+	arch      string
+	pkgName   string
+
+	// pkgs caches the parsed Packages index.
+	pkgs []*AptPackageMeta
+}
+
+func NewApt(mirror, suite, component, arch, pkgName string) *Apt {
+	return &Apt{
+		log:       log.With().Str("provider", string(ProviderApt)).Logger(),
+		mirror:    mirror,
+		suite:     suite,
+		component: component,
+		arch:      arch,
+		pkgName:   pkgName,
+	}
+}
+
+func (a *Apt) buildURL(compression string) (*url.URL, error) {
+	// http://deb.debian.org/debian/dists/buster/main/binary-amd64/Packages.xz
+	path := path.Join("dists", a.suite, a.component, "binary-"+a.arch, aptPackagesFilename+compression)
+	u := fmt.Sprintf("%s/%s", strings.TrimSuffix(a.mirror, "/"), path)
+	return url.Parse(u)
+}
+
+// getPackagesIndex downloads and decompresses the first Packages index found.
+func (a *Apt) getPackagesIndex() ([]byte, error) {
+	var errs []string
+
+	for _, compression := range aptCompressions {
+		indexURL, err := a.buildURL(compression)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := http.DefaultClient.Get(indexURL.String())
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			errs = append(errs, fmt.Sprintf("%d, url=%s", resp.StatusCode, indexURL))
+			continue
+		}
+
+		data, err := decompressIndex(resp.Body, compression)
+		resp.Body.Close()
+		return data, err
+	}
+
+	return nil, fmt.Errorf("error getting packages index file: %s", strings.Join(errs, "; "))
+}
+
+// decompressIndex decompresses an index by the extension of its file, an
+// empty extension is an uncompressed index.
+func decompressIndex(in io.Reader, compression string) ([]byte, error) {
+	var r io.Reader
+	switch compression {
+	case "":
+		r = in
+	case ".gz":
+		gzReader, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, err
+		}
+		defer gzReader.Close()
+		r = gzReader
+	case ".xz":
+		xzReader, err := xz.NewReader(in)
+		if err != nil {
+			return nil, err
+		}
+		r = xzReader
+	default:
+		return nil, fmt.Errorf("compression not supported: %s", compression)
+	}
+	return ioutil.ReadAll(r)
+}
+
+func (a *Apt) getPackages() ([]*AptPackageMeta, error) {
+	if a.pkgs != nil {
+		return a.pkgs, nil
+	}
+
+	index, err := a.getPackagesIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.pkgs, err = parseAptPackages(index); err != nil {
+		return nil, err
+	}
+	return a.pkgs, nil
+}
+
+func (a *Apt) getLatestPackage(scheme core.VersionScheme, semverRange string) (*AptPackageMeta, error) {
+	pkgs, err := a.getPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*AptPackageMeta
+	for _, pkg := range pkgs {
+		if pkg.name == a.pkgName {
+			candidates = append(candidates, pkg)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("Package not found: %s", a.pkgName)
+	}
+
+	pkg, err := selectLatestAptPackage(candidates, a.filter, scheme, semverRange)
+	if err != nil {
+		return nil, err
+	}
+
+	a.log.Info().Str("version", pkg.version).
+		Str("semver", semverRange).
+		Msg("version found")
+
+	return pkg, nil
+}
+
+func (a *Apt) GetFact(fact *core.Fact) (string, error) {
+	pkg, err := a.getLatestPackage(fact.VersionScheme, fact.Semver)
+	if err != nil {
+		return "", err
+	}
+
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		return pkg.version, nil
+	case core.OriginFactKind:
+		return pkg.source, nil
+	case core.ChecksumFactKind:
+		return pkg.sha256, nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderApt, fact.Kind)
+}
+
+type AptPackageMeta struct {
+	name    string
+	version string
+	arch    string
+	// source is the name of the source package, the package name if empty.
+	source   string
+	filename string
+	sha256   string
+}
+
+func (a *AptPackageMeta) isValid() error {
+	errFunc := func(field string) error { return fmt.Errorf("apt package meta field is empty: %s", field) }
+	if a.name == "" {
+		return errFunc("Package")
+	}
+	if a.version == "" {
+		return errFunc("Version")
+	}
+	if a.arch == "" {
+		return errFunc("Architecture")
+	}
+	return nil
+}
+
+// selectLatestAptPackage returns the package with the highest version
+// selected by the filter and satisfying the range, using the dpkg version ordering.
+func selectLatestAptPackage(pkgs []*AptPackageMeta, filter *VersionFilter, scheme core.VersionScheme, semverRange string) (*AptPackageMeta, error) {
+	sorted := make([]*AptPackageMeta, len(pkgs))
+	copy(sorted, pkgs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return CompareDebVersions(sorted[i].version, sorted[j].version) > 0
+	})
+
+	expectedRange, err := parseVersionRange(scheme, semverRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, pkg := range sorted {
+		if !filter.Match(pkg.version) {
+			continue
+		}
+		versions = append(versions, pkg.version)
+		ok, err := expectedRange(filter.Transform(pkg.version))
+		if err != nil {
+			log.Warn().Str("version", pkg.version).
+				Err(err).
+				Msg("parsing version failed")
+			continue
+		}
+		if ok {
+			return pkg, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no version found matching semver, versions=%s, semver='%s'", strings.Join(versions, ","), semverRange)
+}
+
+// parseAptPackages parses the deb822 stanzas of a Packages index.
+func parseAptPackages(index []byte) ([]*AptPackageMeta, error) {
+	sc := bufio.NewScanner(bytes.NewReader(index))
+	// Some fields (e.g Description) exceed the default buffer size.
+	sc.Buffer(make([]byte, bufio.MaxScanTokenSize), 1024*1024)
+
+	var pkgs []*AptPackageMeta
+	p := &AptPackageMeta{}
+
+	endStanza := func() error {
+		if *p == (AptPackageMeta{}) {
+			return nil
+		}
+		if err := p.isValid(); err != nil {
+			return err
+		}
+		if p.source == "" {
+			p.source = p.name
+		}
+		pkgs = append(pkgs, p)
+		p = &AptPackageMeta{}
+		return nil
+	}
+
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			if err := endStanza(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// Continuation of a multiline field.
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid field in stanza of package %s: %s", p.name, line)
+		}
+		value := strings.TrimSpace(line[i+1:])
+
+		switch line[:i] {
+		case "Package":
+			p.name = value
+		case "Version":
+			p.version = value
+		case "Architecture":
+			p.arch = value
+		case "Source":
+			// The source version may follow, e.g "nginx (1.14.2-2)".
+			if fields := strings.Fields(value); len(fields) > 0 {
+				p.source = fields[0]
+			}
+		case "Filename":
+			p.filename = value
+		case "SHA256":
+			p.sha256 = value
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, errors.Wrap(err, "Invalid input")
+	}
+	if err := endStanza(); err != nil {
+		return nil, err
+	}
+
+	return pkgs, nil
+}
@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/ulikunitz/xz"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var _ = Describe("Apt Provider", func() {
+	var index []byte
+
+	BeforeEach(func() {
+		var err error
+		index, err = ioutil.ReadFile("testdata/Packages")
+		Expect(err).To(BeNil())
+	})
+
+	Describe("Parse Packages index", func() {
+		It("parses all the stanzas", func() {
+			pkgs, err := parseAptPackages(index)
+			Expect(err).To(BeNil())
+			Expect(pkgs).To(HaveLen(5))
+			Expect(pkgs[1]).To(Equal(&AptPackageMeta{
+				name:     "nginx-common",
+				version:  "1.14.2-2+deb10u3",
+				arch:     "all",
+				source:   "nginx",
+				filename: "pool/main/n/nginx/nginx-common_1.14.2-2+deb10u3_all.deb",
+				sha256:   "9f2b6e1f7c1e3e5b1d6a0b8f7e6d5c4b3a29180716f5e4d3c2b1a09f8e7d6c5b4",
+			}))
+			Expect(pkgs[4].source).To(Equal("zlib"))
+		})
+		It("fails because of incomplete data", func() {
+			_, err := parseAptPackages([]byte("Package: nginx\nArchitecture: all\n\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Decompress index", func() {
+		It("reads the uncompressed index", func() {
+			data, err := decompressIndex(bytes.NewReader(index), "")
+			Expect(err).To(BeNil())
+			Expect(data).To(Equal(index))
+		})
+		It("fails because of an unsupported compression", func() {
+			_, err := decompressIndex(bytes.NewReader(index), ".bz2")
+			Expect(err).To(MatchError("compression not supported: .bz2"))
+		})
+	})
+
+	Describe("Debian versions", func() {
+		It("compares versions like dpkg", func() {
+			tests := []struct {
+				a, b     string
+				expected int
+			}{
+				{a: "1.0", b: "1.0", expected: 0},
+				{a: "1.0", b: "1.0-0", expected: 0},
+				{a: "1.0", b: "1.1", expected: -1},
+				{a: "1.0-1", b: "1.0-2", expected: -1},
+				{a: "1.0-10", b: "1.0-9", expected: 1},
+				{a: "1:1.0", b: "2.0", expected: 1},
+				{a: "1.0~rc1", b: "1.0", expected: -1},
+				{a: "1.0~rc1", b: "1.0~rc2", expected: -1},
+				{a: "1.0~~", b: "1.0~", expected: -1},
+				{a: "1.0+deb10u1", b: "1.0", expected: 1},
+				{a: "1.0a", b: "1.0+", expected: -1},
+				{a: "1.0.1", b: "1.0a", expected: 1},
+				{a: "7.64.0-4+deb10u2", b: "7.64.0-4+deb10u1", expected: 1},
+				{a: "1.2.11.dfsg-1", b: "1.2.11-1", expected: 1},
+				{a: "0001.0", b: "1.0", expected: 0},
+			}
+			for _, tt := range tests {
+				Expect(CompareDebVersions(tt.a, tt.b)).To(Equal(tt.expected), "%s %s", tt.a, tt.b)
+				Expect(CompareDebVersions(tt.b, tt.a)).To(Equal(-tt.expected), "%s %s", tt.b, tt.a)
+			}
+		})
+		It("validates versions", func() {
+			for _, v := range []string{"1.0", "1:1.2.11.dfsg-1", "1.0~rc1-1", "7.64.0-4+deb10u1"} {
+				Expect(ValidateDebVersion(v)).To(BeTrue(), v)
+			}
+			for _, v := range []string{"", "a1.0", "x:1.0", "1.0-", "1.0 1"} {
+				Expect(ValidateDebVersion(v)).To(BeFalse(), v)
+			}
+		})
+	})
+
+	Describe("Get facts", func() {
+		var server *httptest.Server
+		var compression string
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/dists/buster/main/binary-amd64/Packages"+compression {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				var buf bytes.Buffer
+				switch compression {
+				case ".gz":
+					gzWriter := gzip.NewWriter(&buf)
+					_, _ = gzWriter.Write(index)
+					_ = gzWriter.Close()
+				case ".xz":
+					xzWriter, err := xz.NewWriter(&buf)
+					Expect(err).To(BeNil())
+					_, _ = xzWriter.Write(index)
+					_ = xzWriter.Close()
+				}
+				_, _ = w.Write(buf.Bytes())
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		for _, c := range aptCompressions {
+			c := c
+			It("returns the highest version from "+c+" index", func() {
+				compression = c
+				a := NewApt(server.URL, "buster", "main", "amd64", "curl")
+				value, err := a.GetFact(&core.Fact{Kind: core.VersionFactKind})
+				Expect(err).To(BeNil())
+				Expect(value).To(Equal("7.64.0-4+deb10u2"))
+			})
+		}
+		It("applies the deb version range", func() {
+			compression = ".gz"
+			a := NewApt(server.URL, "buster", "main", "amd64", "curl")
+			value, err := a.GetFact(&core.Fact{Kind: core.VersionFactKind, Semver: "<7.64.0-4+deb10u2", VersionScheme: core.DebVersionScheme})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("7.64.0-4+deb10u1"))
+
+			value, err = a.GetFact(&core.Fact{Kind: core.ChecksumFactKind, Semver: "<7.64.0-4+deb10u2", VersionScheme: core.DebVersionScheme})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("4d8c2a4a4f5b6e5d3c1b7f8e9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b"))
+		})
+		It("falls back to the next version when the filter excludes the highest", func() {
+			compression = ".gz"
+			f, err := NewVersionFilter(core.Source{Exclude: `deb10u2$`})
+			Expect(err).To(BeNil())
+
+			a := NewApt(server.URL, "buster", "main", "amd64", "curl")
+			a.setFilter(f)
+			value, err := a.GetFact(&core.Fact{Kind: core.VersionFactKind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("7.64.0-4+deb10u1"))
+		})
+	})
+})
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+// assetVersion is the data of the asset templates, e.g
+// app_{{ .Version }}_linux_amd64.tar.gz.
+type assetVersion struct {
+	// Version is the canonical version.
+	Version string
+	// RawVersion is the version as published upstream, e.g v1.2.3.
+	RawVersion string
+}
+
+func newAssetVersion(filter *VersionFilter, raw string) assetVersion {
+	return assetVersion{
+		Version:    util.SanitizeVersion(filter.Transform(raw)),
+		RawVersion: raw,
+	}
+}
+
+func renderAssetTemplate(text string, v assetVersion) (string, error) {
+	tmpl, err := template.New("asset").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, v); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// parseChecksums returns the SHA256 of the file name from a checksums
+// file in the sha256sum format, or from a file with only the checksum,
+// e.g app.tar.gz.sha256.
+func parseChecksums(data []byte, name string) (string, error) {
+	var lines [][]string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		if fields := strings.Fields(sc.Text()); len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+
+	for _, fields := range lines {
+		if len(fields) == 1 && len(lines) == 1 {
+			return validateSHA256(fields[0])
+		}
+		// The binary mode is flagged with a *, e.g "<sha256> *app.tar.gz",
+		// and the names may be paths, e.g ./dist/app.tar.gz.
+		if len(fields) == 2 && filepath.Base(strings.TrimPrefix(fields[1], "*")) == name {
+			return validateSHA256(fields[0])
+		}
+	}
+
+	return "", fmt.Errorf("checksum not found for asset: %s", name)
+}
+
+func validateSHA256(s string) (string, error) {
+	s = strings.ToLower(s)
+	if b, err := hex.DecodeString(s); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid sha256 checksum: %s", s)
+	}
+	return s, nil
+}
+
+// DefaultAssetCacheDir returns the default cache directory for the
+// checksums of the downloaded assets.
+func DefaultAssetCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "gojo", "assets")
+}
+
+// cachedAssetSHA256 returns the SHA256 of the asset at url, the asset is
+// downloaded once and its checksum is cached in dir by URL.
+func cachedAssetSHA256(dir, url string, download func() (io.ReadCloser, error)) (string, error) {
+	key := sha256.Sum256([]byte(url))
+	cachePath := filepath.Join(dir, hex.EncodeToString(key[:]))
+
+	if data, err := ioutil.ReadFile(cachePath); err == nil {
+		return validateSHA256(strings.TrimSpace(string(data)))
+	}
+
+	body, err := download()
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := util.WriteToFile(cachePath, []byte(sum+"\n"), 0644); err != nil {
+		return "", err
+	}
+
+	return sum, nil
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Assets", func() {
+	const sum = "d59386e0ae435e292fbe0ebcdb954b75ed5fb3922091277cb19f798fc5d50718"
+
+	It("parses the checksums files", func() {
+		data := []byte(`e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  app_1.0.0_darwin_amd64.tar.gz
+` + sum + ` *dist/app_1.0.0_linux_amd64.tar.gz
+`)
+		value, err := parseChecksums(data, "app_1.0.0_linux_amd64.tar.gz")
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal(sum))
+
+		_, err = parseChecksums(data, "app_1.0.0_windows_amd64.zip")
+		Expect(err).To(HaveOccurred())
+
+		value, err = parseChecksums([]byte(sum+"\n"), "app.tar.gz")
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal(sum))
+
+		_, err = parseChecksums([]byte("not-a-checksum  app.tar.gz\n"), "app.tar.gz")
+		Expect(err).To(HaveOccurred())
+	})
+	It("downloads the asset once", func() {
+		downloads := 0
+		download := func() (io.ReadCloser, error) {
+			downloads++
+			return ioutil.NopCloser(bytes.NewReader([]byte("asset"))), nil
+		}
+
+		dir, err := ioutil.TempDir("", "gojo-assets")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+		for i := 0; i < 2; i++ {
+			value, err := cachedAssetSHA256(dir, "https://example.com/app.tar.gz", download)
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal(sum))
+		}
+		Expect(downloads).To(Equal(1))
+	})
+	It("renders the asset templates", func() {
+		v := newAssetVersion(nil, "v1.2.3")
+		name, err := renderAssetTemplate("app_{{ .Version }}_{{ .RawVersion }}.tar.gz", v)
+		Expect(err).To(BeNil())
+		Expect(name).To(Equal("app_1.2.3_v1.2.3.tar.gz"))
+
+		_, err = renderAssetTemplate("app_{{ .Unknown }}.tar.gz", v)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+// Changelog describes the changes between two versions.
+type Changelog struct {
+	// Notes are the release notes of the new version.
+	Notes string
+	// CompareURL is the URL comparing the old and new versions.
+	CompareURL string
+}
+
+// Changeloger is implemented by the providers publishing the changes
+// between versions, the versions are the raw versions.
+type Changeloger interface {
+	GetChangelog(old, new string) (*Changelog, error)
+}
+
+var _ Changeloger = &GitHub{}
+var _ Changeloger = &Git{}
+
+// GetChangelog returns the notes of the release of the new version and
+// the compare URL of the versions, an old version is optional.
+func (g *GitHub) GetChangelog(old, new string) (*Changelog, error) {
+	ctx := context.Background()
+	changelog := &Changelog{}
+
+	release, resp, err := g.client.Repositories.GetReleaseByTag(ctx, g.owner, g.repository, new)
+	switch {
+	case err == nil:
+		changelog.Notes = strings.TrimSpace(release.GetBody())
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		g.log.Debug().Str("version", new).Msg("no release found")
+	default:
+		return nil, err
+	}
+
+	if old != "" {
+		comparison, _, err := g.client.Repositories.CompareCommits(ctx, g.owner, g.repository, old, new)
+		if err != nil {
+			return nil, err
+		}
+		changelog.CompareURL = comparison.GetHTMLURL()
+	}
+
+	return changelog, nil
+}
+
+// GetChangelog returns the compare URL of the versions of the repositories
+// hosted on GitHub or GitLab and the notes of the GitLab releases.
+func (g *Git) GetChangelog(old, new string) (*Changelog, error) {
+	remote, err := util.ParseGitRemoteURL(g.url)
+	if err != nil {
+		return nil, err
+	}
+	changelog := &Changelog{}
+
+	switch {
+	case remote.Host == "github.com":
+		if old != "" {
+			changelog.CompareURL = fmt.Sprintf("https://%s/%s/compare/%s...%s", remote.Host, remote.Path, old, new)
+		}
+	case strings.Contains(remote.Host, "gitlab"):
+		if old != "" {
+			changelog.CompareURL = fmt.Sprintf("https://%s/%s/-/compare/%s...%s", remote.Host, remote.Path, old, new)
+		}
+		if changelog.Notes, err = getGitLabReleaseNotes(remote, new); err != nil {
+			g.log.Debug().Str("version", new).Err(err).Msg("no release found")
+		}
+	}
+
+	return changelog, nil
+}
+
+// getGitLabReleaseNotes returns the description of the release of a tag,
+// GITLAB_TOKEN is used for the private projects.
+func getGitLabReleaseNotes(remote *util.GitRemote, tag string) (string, error) {
+	u := fmt.Sprintf("https://%s/api/v4/projects/%s/releases/%s", remote.Host, url.PathEscape(remote.Path), url.PathEscape(tag))
+
+	header := http.Header{}
+	if token := os.Getenv(util.GitLabTokenEnvVar); token != "" {
+		header.Set("PRIVATE-TOKEN", token)
+	}
+
+	var release struct {
+		Description string `json:"description"`
+	}
+	if err := getRegistryJSON(u, header, &release); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(release.Description), nil
+}
@@ -3,6 +3,16 @@ package provider
 type ProviderType string
 
 const (
-	ProviderAlpine ProviderType = "alpine"
-	ProviderGitHub ProviderType = "github"
+	ProviderAlpine        ProviderType = "alpine"
+	ProviderAlpineRelease ProviderType = "alpineRelease"
+	ProviderApt           ProviderType = "apt"
+	ProviderRpm           ProviderType = "rpm"
+	ProviderPyPI          ProviderType = "pypi"
+	ProviderNpm           ProviderType = "npm"
+	ProviderCrates        ProviderType = "crates"
+	ProviderGoProxy       ProviderType = "goProxy"
+	ProviderHelm          ProviderType = "helm"
+	ProviderHTTP          ProviderType = "http"
+	ProviderGit           ProviderType = "git"
+	ProviderGitHub        ProviderType = "github"
 )
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+const (
+	cratesDefaultAPI = "https://crates.io/api/v1"
+)
+
+type Crates struct {
+	filtered
+
+	log zerolog.Logger
+
+	api   string
+	crate string
+
+	// versions caches the releases of the crate.
+	versions *Versions
+}
+
+// cratesCrate is the crate document returned by the crates.io API.
+type cratesCrate struct {
+	Versions []struct {
+		Num    string `json:"num"`
+		Yanked bool   `json:"yanked"`
+	} `json:"versions"`
+}
+
+func NewCrates(api, crate string) *Crates {
+	return &Crates{
+		log:   log.With().Str("provider", string(ProviderCrates)).Logger(),
+		api:   strings.TrimSuffix(api, "/"),
+		crate: crate,
+	}
+}
+
+func (c *Crates) GetAll() (*Versions, error) {
+	if c.versions != nil {
+		return c.versions, nil
+	}
+
+	// https://crates.io/api/v1/crates/<crate>
+	var crate cratesCrate
+	if err := getRegistryJSON(fmt.Sprintf("%s/crates/%s", c.api, url.PathEscape(c.crate)), nil, &crate); err != nil {
+		return nil, err
+	}
+
+	var releases []registryRelease
+	for _, v := range crate.Versions {
+		releases = append(releases, registryRelease{version: v.Num, withdrawn: v.Yanked})
+	}
+
+	c.versions = splitReleases(c.log, c.filter.releases(c.log, releases), c.filter.parser(parseSanitizedSemver))
+	return c.versions, nil
+}
+
+func (c *Crates) GetFact(fact *core.Fact) (string, error) {
+	if err := checkSemverScheme(ProviderCrates, fact); err != nil {
+		return "", err
+	}
+
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		v, err := c.GetAll()
+		if err != nil {
+			return "", err
+		}
+		version, err := selectLatestVersion(v, fact.Semver, c.filter.parser(parseSanitizedSemver))
+		if err != nil {
+			return "", err
+		}
+		c.log.Info().Str("version", version).
+			Str("semver", fact.Semver).
+			Msg("version found")
+		return version, nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderCrates, fact.Kind)
+}
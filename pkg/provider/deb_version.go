@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"strings"
+)
+
+// Debian version comparison following dpkg (lib/dpkg/version.c).
+// A version is [epoch:]upstream_version[-debian_revision].
+
+type debVersion struct {
+	epoch    int
+	upstream string
+	revision string
+}
+
+func parseDebVersion(v string) (debVersion, bool) {
+	var d debVersion
+	v = strings.TrimSpace(v)
+
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch := v[:i]
+		if epoch == "" {
+			return d, false
+		}
+		for j := 0; j < len(epoch); j++ {
+			if !isDigit(epoch[j]) {
+				return d, false
+			}
+			d.epoch = d.epoch*10 + int(epoch[j]-'0')
+		}
+		v = v[i+1:]
+	}
+
+	d.upstream = v
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		d.upstream = v[:i]
+		d.revision = v[i+1:]
+		if d.revision == "" {
+			return d, false
+		}
+	}
+
+	if d.upstream == "" || !isDigit(d.upstream[0]) {
+		return d, false
+	}
+	for _, c := range d.upstream + d.revision {
+		if !(isDigit(byte(c)) || isLower(byte(c)) || (c >= 'A' && c <= 'Z') || strings.ContainsRune(".+~-:", c)) {
+			return d, false
+		}
+	}
+
+	return d, true
+}
+
+// ValidateDebVersion returns true if the version is a valid Debian version.
+func ValidateDebVersion(v string) bool {
+	_, ok := parseDebVersion(v)
+	return ok
+}
+
+func debOrder(c byte) int {
+	switch {
+	case isDigit(c):
+		return 0
+	case isLower(c) || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	case c == '~':
+		return -1
+	case c != 0:
+		return int(c) + 256
+	}
+	return 0
+}
+
+func debVerRevCmp(a, b string) int {
+	at := func(s string, i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return 0
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		firstDiff := 0
+
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := debOrder(at(a, i)), debOrder(at(b, j))
+			if ac != bc {
+				return ac - bc
+			}
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		for i < len(a) && j < len(b) && isDigit(a[i]) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+			i++
+			j++
+		}
+
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+
+	return 0
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
+
+// CompareDebVersions compares two Debian package versions using the dpkg
+// ordering. It returns -1 if a < b, 0 if a == b and 1 if a > b.
+func CompareDebVersions(a, b string) int {
+	va, _ := parseDebVersion(a)
+	vb, _ := parseDebVersion(b)
+
+	if va.epoch != vb.epoch {
+		return sign(va.epoch - vb.epoch)
+	}
+	if r := debVerRevCmp(va.upstream, vb.upstream); r != 0 {
+		return sign(r)
+	}
+	return sign(debVerRevCmp(va.revision, vb.revision))
+}
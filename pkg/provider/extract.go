@@ -0,0 +1,360 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// extractor returns the values found in a document.
+type extractor func(data []byte) ([]string, error)
+
+// newRegexExtractor returns the first capture group of all the matches.
+func newRegexExtractor(expr string) (extractor, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	if re.NumSubexp() < 1 {
+		return nil, fmt.Errorf("regex without capture group: %s", expr)
+	}
+
+	return func(data []byte) ([]string, error) {
+		var values []string
+		for _, m := range re.FindAllSubmatch(data, -1) {
+			values = append(values, string(m[1]))
+		}
+		return values, nil
+	}, nil
+}
+
+// jsonPathStep is a step of a JSONPath expression.
+type jsonPathStep struct {
+	// name is the member name, empty for an index or a wildcard.
+	name     string
+	index    int
+	wildcard bool
+	// descend selects the node and all its descendants.
+	descend bool
+}
+
+var jsonPathIndexRegexp = regexp.MustCompile(`^\[(\*|-?\d+|'[^']*'|"[^"]*")\]`)
+var jsonPathNameRegexp = regexp.MustCompile(`^(\.\.?)(\*|[^.\[]+)?`)
+
+// parseJSONPath parses a subset of JSONPath: the root $, the members .name
+// and ['name'], the indexes [0] and [-1], the wildcards .* and [*] and the
+// recursive descent ..name, e.g $.items[*].version or $..tag_name.
+func parseJSONPath(expr string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("JSONPath must start with $: %s", expr)
+	}
+
+	var steps []jsonPathStep
+	s := expr[1:]
+	for s != "" {
+		if m := jsonPathIndexRegexp.FindStringSubmatch(s); m != nil {
+			s = s[len(m[0]):]
+			switch sel := m[1]; {
+			case sel == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case sel[0] == '\'' || sel[0] == '"':
+				steps = append(steps, jsonPathStep{name: sel[1 : len(sel)-1]})
+			default:
+				index, err := strconv.Atoi(sel)
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, jsonPathStep{index: index})
+			}
+			continue
+		}
+
+		m := jsonPathNameRegexp.FindStringSubmatch(s)
+		if m == nil || (m[1] == "." && m[2] == "") {
+			return nil, fmt.Errorf("invalid JSONPath: %s", expr)
+		}
+		s = s[len(m[0]):]
+		if m[1] == ".." {
+			steps = append(steps, jsonPathStep{descend: true})
+		}
+		switch m[2] {
+		case "":
+		case "*":
+			steps = append(steps, jsonPathStep{wildcard: true})
+		default:
+			steps = append(steps, jsonPathStep{name: m[2]})
+		}
+	}
+
+	return steps, nil
+}
+
+// children returns the values selected by the step in a node.
+func (s jsonPathStep) children(node interface{}) []interface{} {
+	if s.descend {
+		return descendants(node)
+	}
+
+	var selected []interface{}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			keys := make([]string, 0, len(n))
+			for k := range n {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				selected = append(selected, n[k])
+			}
+		} else if v, ok := n[s.name]; ok && s.name != "" {
+			selected = append(selected, v)
+		}
+	case []interface{}:
+		switch {
+		case s.wildcard:
+			selected = append(selected, n...)
+		case s.name == "":
+			index := s.index
+			if index < 0 {
+				index += len(n)
+			}
+			if index >= 0 && index < len(n) {
+				selected = append(selected, n[index])
+			}
+		}
+	}
+	return selected
+}
+
+// descendants returns the node and all its descendants.
+func descendants(node interface{}) []interface{} {
+	nodes := []interface{}{node}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			nodes = append(nodes, descendants(n[k])...)
+		}
+	case []interface{}:
+		for _, v := range n {
+			nodes = append(nodes, descendants(v)...)
+		}
+	}
+	return nodes
+}
+
+// newJSONPathExtractor returns the scalar values selected by the JSONPath.
+func newJSONPathExtractor(expr string) (extractor, error) {
+	steps, err := parseJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(data []byte) ([]string, error) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		// keep the numbers as written, e.g 1.10 is not 1.1
+		decoder.UseNumber()
+		var root interface{}
+		if err := decoder.Decode(&root); err != nil {
+			return nil, err
+		}
+
+		nodes := []interface{}{root}
+		for _, step := range steps {
+			var next []interface{}
+			for _, node := range nodes {
+				next = append(next, step.children(node)...)
+			}
+			nodes = next
+		}
+
+		var values []string
+		for _, node := range nodes {
+			switch v := node.(type) {
+			case string:
+				values = append(values, v)
+			case json.Number:
+				values = append(values, v.String())
+			case bool:
+				values = append(values, strconv.FormatBool(v))
+			}
+		}
+		return values, nil
+	}, nil
+}
+
+// xmlNode is an element of a XML document.
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*xmlNode
+}
+
+func parseXMLTree(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// RSS feeds are not always UTF-8, the values are ASCII anyway.
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	decoder.Strict = false
+
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, attrs: make(map[string]string)}
+			for _, a := range t.Attr {
+				node.attrs[a.Name.Local] = a.Value
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.text += string(t)
+		}
+	}
+
+	return root, nil
+}
+
+// xPathStep is a step of a XPath-lite expression.
+type xPathStep struct {
+	name string
+	// position is the 1-based position of the element, 0 for all.
+	position   int
+	descendant bool
+}
+
+var xPathStepRegexp = regexp.MustCompile(`^([\w.-]+|\*)(?:\[(\d+)\])?$`)
+
+// parseXPath parses a subset of XPath: absolute paths of element names,
+// the descendants //, the wildcard *, the positions [1] and a final
+// attribute or text() step, e.g //item/title or /feed/entry[1]/link/@href.
+func parseXPath(expr string) ([]xPathStep, string, error) {
+	if !strings.HasPrefix(expr, "/") {
+		return nil, "", fmt.Errorf("XPath must be absolute: %s", expr)
+	}
+
+	var steps []xPathStep
+	var attr string
+	descendant := false
+	parts := strings.Split(expr[1:], "/")
+	for i, part := range parts {
+		if part == "" {
+			descendant = true
+			continue
+		}
+		last := i == len(parts)-1
+		if last && strings.HasPrefix(part, "@") {
+			attr = part[1:]
+			break
+		}
+		if last && part == "text()" {
+			break
+		}
+		m := xPathStepRegexp.FindStringSubmatch(part)
+		if m == nil {
+			return nil, "", fmt.Errorf("invalid XPath step %s: %s", part, expr)
+		}
+		position := 0
+		if m[2] != "" {
+			position, _ = strconv.Atoi(m[2])
+		}
+		steps = append(steps, xPathStep{name: m[1], position: position, descendant: descendant})
+		descendant = false
+	}
+	if len(steps) == 0 {
+		return nil, "", fmt.Errorf("invalid XPath: %s", expr)
+	}
+
+	return steps, attr, nil
+}
+
+func (s xPathStep) match(node *xmlNode) []*xmlNode {
+	candidates := node.children
+	if s.descendant {
+		candidates = nil
+		var walk func(*xmlNode)
+		walk = func(n *xmlNode) {
+			for _, c := range n.children {
+				candidates = append(candidates, c)
+				walk(c)
+			}
+		}
+		walk(node)
+	}
+
+	var matched []*xmlNode
+	for _, c := range candidates {
+		if s.name == "*" || c.name == s.name {
+			matched = append(matched, c)
+		}
+	}
+	if s.position > 0 {
+		if s.position > len(matched) {
+			return nil
+		}
+		return matched[s.position-1 : s.position]
+	}
+	return matched
+}
+
+// newXPathExtractor returns the texts or the attributes selected by the
+// XPath-lite expression.
+func newXPathExtractor(expr string) (extractor, error) {
+	steps, attr, err := parseXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(data []byte) ([]string, error) {
+		root, err := parseXMLTree(data)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes := []*xmlNode{root}
+		for _, step := range steps {
+			var next []*xmlNode
+			for _, node := range nodes {
+				next = append(next, step.match(node)...)
+			}
+			nodes = next
+		}
+
+		var values []string
+		for _, node := range nodes {
+			if attr != "" {
+				if v, ok := node.attrs[attr]; ok {
+					values = append(values, v)
+				}
+				continue
+			}
+			values = append(values, strings.TrimSpace(node.text))
+		}
+		return values, nil
+	}, nil
+}
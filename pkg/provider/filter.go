@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"regexp"
+
+	"github.com/blang/semver/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+// VersionFilter selects the raw versions of a source with the include and
+// exclude regexes and transforms them to canonical versions, e.g
+// haproxy-2_3_5 to 2.3.5. A nil filter selects every version as is.
+type VersionFilter struct {
+	include   *regexp.Regexp
+	exclude   *regexp.Regexp
+	transform *regexp.Regexp
+	replace   string
+}
+
+// NewVersionFilter returns the filter of the source, nil if the source
+// has no filter.
+func NewVersionFilter(source core.Source) (*VersionFilter, error) {
+	if source.Include == "" && source.Exclude == "" && source.Transform == nil {
+		return nil, nil
+	}
+
+	f := &VersionFilter{}
+	var err error
+	if source.Include != "" {
+		if f.include, err = regexp.Compile(source.Include); err != nil {
+			return nil, err
+		}
+	}
+	if source.Exclude != "" {
+		if f.exclude, err = regexp.Compile(source.Exclude); err != nil {
+			return nil, err
+		}
+	}
+	if source.Transform != nil {
+		if f.transform, err = regexp.Compile(source.Transform.Regex); err != nil {
+			return nil, err
+		}
+		f.replace = source.Transform.Replace
+	}
+
+	return f, nil
+}
+
+// Match returns true if the raw version is selected by the filter.
+func (f *VersionFilter) Match(raw string) bool {
+	if f == nil {
+		return true
+	}
+	if f.include != nil && !f.include.MatchString(raw) {
+		return false
+	}
+	if f.exclude != nil && f.exclude.MatchString(raw) {
+		return false
+	}
+	return true
+}
+
+// Transform returns the canonical version of a raw version, the raw
+// version is returned if it does not match the transform regex.
+func (f *VersionFilter) Transform(raw string) string {
+	if f == nil || f.transform == nil || !f.transform.MatchString(raw) {
+		return raw
+	}
+	return f.transform.ReplaceAllString(raw, f.replace)
+}
+
+// releases returns the releases selected by the filter.
+func (f *VersionFilter) releases(logger zerolog.Logger, releases []registryRelease) []registryRelease {
+	if f == nil {
+		return releases
+	}
+
+	var selected []registryRelease
+	for _, r := range releases {
+		if !f.Match(r.version) {
+			logger.Debug().Str("version", r.version).Msg("version filtered out")
+			continue
+		}
+		selected = append(selected, r)
+	}
+	return selected
+}
+
+// parser returns a parser of the raw versions, they are transformed
+// before being parsed.
+func (f *VersionFilter) parser(parse versionParser) versionParser {
+	if f == nil || f.transform == nil {
+		return parse
+	}
+	return func(version string) (semver.Version, error) {
+		return parse(f.Transform(version))
+	}
+}
+
+// filtered is embedded by the providers selecting a version among
+// candidates so the filter of the source can be set.
+type filtered struct {
+	filter *VersionFilter
+}
+
+func (f *filtered) setFilter(filter *VersionFilter) {
+	f.filter = filter
+}
+
+// filterable is implemented by the providers embedding filtered.
+type filterable interface {
+	setFilter(filter *VersionFilter)
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rs/zerolog"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+var _ = Describe("Version Filter", func() {
+	It("selects and transforms the raw versions", func() {
+		f, err := NewVersionFilter(core.Source{
+			Include:   `^haproxy-`,
+			Exclude:   `-dev`,
+			Transform: &core.Transform{Regex: `^haproxy-(\d+)_(\d+)_(\d+)$`, Replace: "$1.$2.$3"},
+		})
+		Expect(err).To(BeNil())
+
+		Expect(f.Match("haproxy-2_3_5")).To(BeTrue())
+		Expect(f.Match("haproxy-2_4_0-dev")).To(BeFalse())
+		Expect(f.Match("nginx-1_19_0")).To(BeFalse())
+		Expect(f.Transform("haproxy-2_3_5")).To(Equal("2.3.5"))
+		Expect(f.Transform("other")).To(Equal("other"))
+
+		releases := []registryRelease{{version: "haproxy-2_3_5"}, {version: "haproxy-2_3_10"}, {version: "haproxy-2_4_0-dev"}, {version: "nginx-1_19_0"}}
+		v := splitReleases(zerolog.Nop(), f.releases(zerolog.Nop(), releases), f.parser(parseSanitizedSemver))
+		Expect(v.stable).To(Equal([]string{"haproxy-2_3_10", "haproxy-2_3_5"}))
+
+		version, err := selectLatestVersion(v, "<2.3.10", f.parser(parseSanitizedSemver))
+		Expect(err).To(BeNil())
+		Expect(version).To(Equal("haproxy-2_3_5"))
+	})
+	It("selects the versions of a monorepo", func() {
+		f, err := NewVersionFilter(core.Source{
+			Include:   `^cli/`,
+			Transform: &core.Transform{Regex: `^cli/(.*)$`, Replace: "$1"},
+		})
+		Expect(err).To(BeNil())
+
+		releases := []registryRelease{{version: "cli/v1.4.0"}, {version: "web/v2.0.0"}, {version: "cli/v1.3.2"}}
+		v := splitReleases(zerolog.Nop(), f.releases(zerolog.Nop(), releases), f.parser(parseSanitizedSemver))
+		Expect(v.stable).To(Equal([]string{"cli/v1.4.0", "cli/v1.3.2"}))
+		Expect(util.SanitizeVersion(f.Transform(v.stable[0]))).To(Equal("1.4.0"))
+	})
+	It("is a no-op without filter", func() {
+		f, err := NewVersionFilter(core.Source{})
+		Expect(err).To(BeNil())
+		Expect(f).To(BeNil())
+		Expect(f.Match("anything")).To(BeTrue())
+		Expect(f.Transform("v1.0.0")).To(Equal("v1.0.0"))
+
+		_, err = NewVersionFilter(core.Source{Include: `(`})
+		Expect(err).To(HaveOccurred())
+	})
+})
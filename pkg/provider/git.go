@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+type Git struct {
+	filtered
+
+	log zerolog.Logger
+
+	url      string
+	branches bool
+	branch   string
+
+	// listRefs lists the remote references, like git ls-remote.
+	listRefs func() ([]*plumbing.Reference, error)
+	// refs caches the remote references.
+	refs []*plumbing.Reference
+}
+
+func NewGit(source *core.GitSource) (*Git, error) {
+	g := &Git{
+		log:      log.With().Str("provider", string(ProviderGit)).Logger(),
+		url:      source.URL,
+		branches: source.Branches,
+		branch:   source.Branch,
+	}
+
+	g.listRefs = func() ([]*plumbing.Reference, error) {
+		// The references are listed without cloning.
+		remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+			Name: git.DefaultRemoteName,
+			URLs: []string{g.url},
+		})
+		return remote.List(&git.ListOptions{})
+	}
+
+	return g, nil
+}
+
+func (g *Git) getRefs() ([]*plumbing.Reference, error) {
+	if g.refs != nil {
+		return g.refs, nil
+	}
+
+	refs, err := g.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	// The references are listed in a random order.
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name() < refs[j].Name() })
+
+	g.refs = refs
+	return g.refs, nil
+}
+
+// GetAll returns the tags, and the branches if enabled, selected by the
+// filter of the source, split in stable and unstable versions.
+func (g *Git) GetAll() (*Versions, error) {
+	refs, err := g.getRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []registryRelease
+	for _, ref := range refs {
+		var name string
+		switch {
+		case ref.Name().IsTag():
+			name = ref.Name().Short()
+		case g.branches && ref.Name().IsBranch():
+			name = ref.Name().Short()
+		default:
+			continue
+		}
+		releases = append(releases, registryRelease{version: name})
+	}
+
+	return splitReleases(g.log, g.filter.releases(g.log, releases), g.filter.parser(parseSanitizedSemver)), nil
+}
+
+// getBranchHead returns the SHA of the head of the branch, the branch the
+// remote HEAD points to by default.
+func (g *Git) getBranchHead() (string, error) {
+	refs, err := g.getRefs()
+	if err != nil {
+		return "", err
+	}
+
+	name := plumbing.HEAD
+	if g.branch != "" {
+		name = plumbing.NewBranchReferenceName(g.branch)
+	}
+
+	// The symbolic references, e.g HEAD, are resolved once.
+	for i := 0; i < 2; i++ {
+		var found *plumbing.Reference
+		for _, ref := range refs {
+			if ref.Name() == name {
+				found = ref
+				break
+			}
+		}
+		if found == nil {
+			return "", fmt.Errorf("reference not found: %s, url=%s", name, g.url)
+		}
+		if found.Type() == plumbing.HashReference {
+			return found.Hash().String(), nil
+		}
+		name = found.Target()
+	}
+
+	return "", fmt.Errorf("reference can't be resolved: %s, url=%s", name, g.url)
+}
+
+func (g *Git) GetFact(fact *core.Fact) (string, error) {
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		if err := checkSemverScheme(ProviderGit, fact); err != nil {
+			return "", err
+		}
+		v, err := g.GetAll()
+		if err != nil {
+			return "", err
+		}
+		version, err := selectLatestVersion(v, fact.Semver, g.filter.parser(parseSanitizedSemver))
+		if err != nil {
+			return "", err
+		}
+		g.log.Info().Str("version", version).
+			Str("semver", fact.Semver).
+			Msg("version found")
+		return version, nil
+	case core.CommitFactKind:
+		commit, err := g.getBranchHead()
+		if err != nil {
+			return "", err
+		}
+		g.log.Info().Str("commit", commit).
+			Str("branch", g.branch).
+			Msg("commit found")
+		return commit, nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderGit, fact.Kind)
+}
@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"github.com/go-git/go-git/v5/plumbing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var _ = Describe("Git Provider", func() {
+	refs := []*plumbing.Reference{
+		plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main")),
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), plumbing.NewHash("1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d")),
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("stable"), plumbing.NewHash("0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c")),
+		plumbing.NewHashReference(plumbing.NewBranchReferenceName("v9.0.0"), plumbing.NewHash("0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c")),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.2.0"), plumbing.NewHash("2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e")),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("v1.10.0"), plumbing.NewHash("3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f")),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("v2.0.0-rc.1"), plumbing.NewHash("4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f70")),
+		plumbing.NewHashReference(plumbing.NewTagReferenceName("chart-3.0.0"), plumbing.NewHash("5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f7081")),
+	}
+
+	newGit := func(source *core.GitSource) *Git {
+		g, err := NewGit(source)
+		Expect(err).To(BeNil())
+		g.listRefs = func() ([]*plumbing.Reference, error) { return refs, nil }
+		return g
+	}
+
+	It("returns the latest tag", func() {
+		g := newGit(&core.GitSource{URL: "https://git.example.com/app.git"})
+		value, err := g.GetFact(&core.Fact{Kind: core.VersionFactKind})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("v1.10.0"))
+
+		value, err = g.GetFact(&core.Fact{Kind: core.VersionFactKind, Semver: "<1.10.0"})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("v1.2.0"))
+	})
+	It("applies the filter of the source", func() {
+		newFilteredGit := func(source core.Source) *Git {
+			filter, err := NewVersionFilter(source)
+			Expect(err).To(BeNil())
+			g := newGit(source.Git)
+			g.setFilter(filter)
+			return g
+		}
+
+		g := newFilteredGit(core.Source{
+			Include:   `^chart-`,
+			Transform: &core.Transform{Regex: `^chart-(.*)$`, Replace: "$1"},
+			Provider:  core.Provider{Git: &core.GitSource{URL: "https://git.example.com/app.git"}},
+		})
+		value, err := g.GetFact(&core.Fact{Kind: core.VersionFactKind})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("chart-3.0.0"))
+
+		g = newFilteredGit(core.Source{
+			Exclude:  `^v1\.`,
+			Provider: core.Provider{Git: &core.GitSource{URL: "https://git.example.com/app.git", Branches: true}},
+		})
+		value, err = g.GetFact(&core.Fact{Kind: core.VersionFactKind})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("v9.0.0"))
+	})
+	It("resolves the head of a branch", func() {
+		g := newGit(&core.GitSource{URL: "https://git.example.com/app.git"})
+		value, err := g.GetFact(&core.Fact{Kind: core.CommitFactKind})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d"))
+
+		g = newGit(&core.GitSource{URL: "https://git.example.com/app.git", Branch: "stable"})
+		value, err = g.GetFact(&core.Fact{Kind: core.CommitFactKind})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c"))
+
+		g = newGit(&core.GitSource{URL: "https://git.example.com/app.git", Branch: "unknown"})
+		_, err = g.GetFact(&core.Fact{Kind: core.CommitFactKind})
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns the compare URL of the versions", func() {
+		g := newGit(&core.GitSource{URL: "https://github.com/owner/repo.git"})
+		changelog, err := g.GetChangelog("v1.2.0", "v1.10.0")
+		Expect(err).To(BeNil())
+		Expect(changelog.CompareURL).To(Equal("https://github.com/owner/repo/compare/v1.2.0...v1.10.0"))
+	})
+})
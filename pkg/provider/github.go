@@ -3,60 +3,69 @@ package provider
 import (
 	"context"
 	"fmt"
-	"os"
-	"strings"
+	"io"
+	"io/ioutil"
+	"net/http"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
-	"github.com/blang/semver/v4"
 	"github.com/google/go-github/v33/github"
-	"golang.org/x/oauth2"
 
 	"github.com/spiarh/gojo/pkg/core"
-	"github.com/spiarh/gojo/pkg/util"
 )
 
 const (
-	gitHubTokenEnvVar = "GITHUB_TOKEN"
+	// gitHubPerPage is the maximum page size of the API.
+	gitHubPerPage = 100
+	// gitHubDefaultLimit caps the releases or tags retrieved.
+	gitHubDefaultLimit = 1000
 )
 
 type GitHub struct {
+	filtered
+
 	client *github.Client
 	log    zerolog.Logger
 
 	owner      string            `yaml:"owner"`
 	repository string            `yaml:"repository"`
 	object     core.GitHubObject `yaml:"repository"`
+	// limit is the maximum number of releases or tags retrieved.
+	limit int
+	// checksums is the template of the name of the checksums asset.
+	checksums string
+	// cacheDir caches the checksums of the downloaded assets.
+	cacheDir string
+
+	// versions caches the releases or tags of the repository.
+	versions *Versions
 }
 
 type Versions struct {
 	stable   []string
 	unstable []string
+	// invalid are the versions which can't be parsed.
+	invalid []string
 }
 
-func newGitHubClient() *github.Client {
-	token := os.Getenv(gitHubTokenEnvVar)
-	if token == "" {
-		return github.NewClient(nil)
+func NewGitHub(source *core.GitHubSource) (*GitHub, error) {
+	g := &GitHub{
+		log:        log.With().Str("provider", string(ProviderGitHub)).Logger(),
+		owner:      source.Owner,
+		repository: source.Repository,
+		object:     source.Object,
+		limit:      source.Limit,
+		checksums:  source.Checksums,
+		cacheDir:   DefaultAssetCacheDir(),
 	}
 
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-
-	return github.NewClient(oauth2.NewClient(ctx, ts))
-}
-
-func NewGitHub(owner, repo string, object core.GitHubObject) *GitHub {
-	return &GitHub{
-		client:     newGitHubClient(),
-		log:        log.With().Str("provider", string(ProviderGitHub)).Logger(),
-		owner:      owner,
-		repository: repo,
-		object:     object,
+	var err error
+	if g.client, err = newGitHubClient(g.log, source); err != nil {
+		return nil, err
 	}
+
+	return g, nil
 }
 
 func (g *GitHub) GetLatest(semverRange string) (string, error) {
@@ -66,58 +75,127 @@ func (g *GitHub) GetLatest(semverRange string) (string, error) {
 		return "", err
 	}
 
-	if len(v.stable) == 0 {
-		return "", fmt.Errorf("no stable versions found")
+	version, err := selectLatestVersion(v, semverRange, g.filter.parser(parseSanitizedSemver))
+	if err != nil {
+		return "", err
+	}
+
+	g.log.Info().Str("version", version).
+		Str("semver", semverRange).
+		Msg("version found")
+
+	return version, nil
+}
+
+func (g *GitHub) GetFact(fact *core.Fact) (string, error) {
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		return g.GetLatest(fact.Semver)
+	case core.AssetURLFactKind, core.AssetSHA256FactKind:
+		return g.getAssetFact(fact)
+	}
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderGitHub, fact.Kind)
+}
+
+// getAssetFact returns the download URL or the SHA256 of the asset of
+// the latest release.
+func (g *GitHub) getAssetFact(fact *core.Fact) (string, error) {
+	tag, err := g.GetLatest(fact.Semver)
+	if err != nil {
+		return "", err
+	}
+
+	release, _, err := g.client.Repositories.GetReleaseByTag(context.Background(), g.owner, g.repository, tag)
+	if err != nil {
+		return "", err
 	}
 
-	var version string
-	if semverRange == "" {
-		version = v.stable[0]
+	v := newAssetVersion(g.filter, tag)
+	name, err := renderAssetTemplate(fact.Asset, v)
+	if err != nil {
+		return "", err
+	}
+	asset, err := findReleaseAsset(release, name)
+	if err != nil {
+		return "", err
 	}
-	if semverRange != "" {
-		expectedRange, err := semver.ParseRange(semverRange)
+
+	if fact.Kind == core.AssetURLFactKind {
+		return asset.GetBrowserDownloadURL(), nil
+	}
+
+	var sum string
+	if g.checksums != "" {
+		checksumsName, err := renderAssetTemplate(g.checksums, v)
 		if err != nil {
 			return "", err
 		}
-
-		for _, ver := range v.stable {
-			v, err := semver.Parse(util.SanitizeVersion(ver))
-			if err != nil {
-				return "", err
-			}
-			if expectedRange(v) {
-				version = ver
-				break
-			}
+		checksumsAsset, err := findReleaseAsset(release, checksumsName)
+		if err != nil {
+			return "", err
+		}
+		body, err := g.downloadAsset(checksumsAsset)
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		if sum, err = parseChecksums(data, name); err != nil {
+			return "", err
+		}
+	} else {
+		download := func() (io.ReadCloser, error) {
+			g.log.Info().Str("asset", name).Msg("download asset to compute its checksum")
+			return g.downloadAsset(asset)
+		}
+		if sum, err = cachedAssetSHA256(g.cacheDir, asset.GetBrowserDownloadURL(), download); err != nil {
+			return "", err
 		}
-	}
-	if version == "" {
-		return "", fmt.Errorf("no version found matching semver, semver='%s'", semverRange)
 	}
 
-	g.log.Info().Str("version", version).
-		Str("semver", semverRange).
-		Msg("version found")
+	g.log.Info().Str("asset", name).
+		Str("sha256", sum).
+		Msg("asset checksum found")
+	return sum, nil
+}
 
-	return version, nil
+func findReleaseAsset(release *github.RepositoryRelease, name string) (*github.ReleaseAsset, error) {
+	for _, a := range release.Assets {
+		if a.GetName() == name {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("asset not found in release %s: %s", release.GetTagName(), name)
 }
 
+// downloadAsset downloads an asset with the API so the assets of the
+// private repositories can be downloaded.
+func (g *GitHub) downloadAsset(asset *github.ReleaseAsset) (io.ReadCloser, error) {
+	body, _, err := g.client.Repositories.DownloadReleaseAsset(context.Background(), g.owner, g.repository, asset.GetID(), http.DefaultClient)
+	return body, err
+}
+
+// GetAll returns the versions sorted highest first, the releases flagged
+// as prerelease are unstable.
 func (g *GitHub) GetAll() (*Versions, error) {
-	var v Versions
+	if g.versions != nil {
+		return g.versions, nil
+	}
+
+	var releases []registryRelease
 
 	switch g.object {
 	case core.GitHubObjectRelease:
-		releases, err := g.getRepoReleases()
+		repoReleases, err := g.getRepoReleases()
 		if err != nil {
 			return nil, err
 		}
 
-		for _, r := range releases {
-			if *r.Prerelease {
-				v.unstable = append(v.unstable, *r.TagName)
-				continue
-			}
-			v.stable = append(v.stable, *r.TagName)
+		for _, r := range repoReleases {
+			releases = append(releases, registryRelease{version: r.GetTagName(), prerelease: r.GetPrerelease()})
 		}
 	case core.GitHubObjectTag:
 		tags, err := g.getRepoTags()
@@ -126,60 +204,85 @@ func (g *GitHub) GetAll() (*Versions, error) {
 		}
 
 		for _, t := range tags {
-			tagName := util.SanitizeVersion(*t.Name)
-			ver, err := semver.Parse(tagName)
-			if err != nil {
-				g.log.Warn().
-					Str("tag", tagName).
-					Err(err).
-					Msg("parsing tag name failed")
-				v.unstable = append(v.unstable, *t.Name)
-				continue
-			}
-			if len(ver.Pre) > 0 {
-				v.unstable = append(v.unstable, *t.Name)
-				continue
-			}
-			v.stable = append(v.stable, *t.Name)
+			releases = append(releases, registryRelease{version: t.GetName()})
 		}
 	default:
 		return nil, fmt.Errorf("github object type not recognized: %s", string(g.object))
 	}
 
-	g.log.Info().Int("len", len(v.stable)).
-		Str("version", strings.Join(v.stable, ",")).
-		Msg("stable versions")
+	g.versions = splitReleases(g.log, g.filter.releases(g.log, releases), g.filter.parser(parseSanitizedSemver))
+	return g.versions, nil
+}
+
+// listPages calls list with each page until the last one or until the
+// limit is reached, list returns the number of items of the page.
+func (g *GitHub) listPages(list func(opt *github.ListOptions) (int, *github.Response, error)) error {
+	opt := &github.ListOptions{PerPage: gitHubPerPage}
+	total := 0
+	for {
+		n, resp, err := list(opt)
+		if err != nil {
+			return err
+		}
+		total += n
 
-	g.log.Info().Int("len", len(v.unstable)).
-		Str("version", strings.Join(v.unstable, ",")).
-		Msg("unstable versions")
+		if resp.NextPage == 0 || total >= g.limit {
+			g.log.Info().Int("remaining", resp.Rate.Remaining).
+				Int("limit", resp.Rate.Limit).
+				Time("reset", resp.Rate.Reset.Time).
+				Msg("github rate limit")
+		}
 
-	return &v, nil
+		if resp.NextPage == 0 {
+			return nil
+		}
+		if total >= g.limit {
+			g.log.Warn().Int("limit", g.limit).
+				Msg("limit reached, the older versions are ignored")
+			return nil
+		}
+		opt.Page = resp.NextPage
+	}
 }
 
 func (g *GitHub) getRepoReleases() ([]*github.RepositoryRelease, error) {
-	opt := &github.ListOptions{PerPage: 30}
-	releases, _, err := g.client.Repositories.ListReleases(
-		context.Background(),
-		g.owner, g.repository,
-		opt,
-	)
+	var releases []*github.RepositoryRelease
+	err := g.listPages(func(opt *github.ListOptions) (int, *github.Response, error) {
+		page, resp, err := g.client.Repositories.ListReleases(
+			context.Background(),
+			g.owner, g.repository,
+			opt,
+		)
+		releases = append(releases, page...)
+		return len(page), resp, err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if len(releases) > g.limit {
+		releases = releases[:g.limit]
+	}
 	return releases, nil
 }
 
 func (g *GitHub) getRepoTags() ([]*github.RepositoryTag, error) {
-	opt := &github.ListOptions{PerPage: 30}
-	releases, _, err := g.client.Repositories.ListTags(
-		context.Background(),
-		g.owner, g.repository,
-		opt,
-	)
+	var tags []*github.RepositoryTag
+	err := g.listPages(func(opt *github.ListOptions) (int, *github.Response, error) {
+		page, resp, err := g.client.Repositories.ListTags(
+			context.Background(),
+			g.owner, g.repository,
+			opt,
+		)
+		tags = append(tags, page...)
+		return len(page), resp, err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return releases, nil
+
+	if len(tags) > g.limit {
+		tags = tags[:g.limit]
+	}
+	return tags, nil
 }
@@ -0,0 +1,399 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/rs/zerolog"
+	"golang.org/x/oauth2"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/metrics"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+const (
+	gitHubAPIHost = "api.github.com"
+	netrcEnvVar   = "NETRC"
+	// gitHubAppJWTLifetime is the lifetime of the JWT of an app, 10
+	// minutes at most.
+	gitHubAppJWTLifetime = 9 * time.Minute
+	// gitHubMaxRetries is the number of retries on secondary rate limits.
+	gitHubMaxRetries   = 5
+	gitHubMaxRetryWait = 2 * time.Minute
+)
+
+// newGitHubClient returns a client of the API of the source, authenticated
+// in order with the GitHub App, the token env var of the source, netrc
+// or GITHUB_TOKEN.
+func newGitHubClient(logger zerolog.Logger, source *core.GitHubSource) (*github.Client, error) {
+	transport := &gitHubRateLimitTransport{
+		log:   logger,
+		base:  http.DefaultTransport,
+		sleep: time.Sleep,
+	}
+
+	apiURL, err := url.Parse("https://" + gitHubAPIHost + "/")
+	if source.BaseURL != "" {
+		apiURL, err = url.Parse(source.BaseURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	newClient := func(httpClient *http.Client) (*github.Client, error) {
+		if source.BaseURL == "" {
+			return github.NewClient(httpClient), nil
+		}
+		return github.NewEnterpriseClient(source.BaseURL, source.BaseURL, httpClient)
+	}
+
+	var httpClient *http.Client
+	switch {
+	case source.App != nil:
+		ts, err := newGitHubAppTokenSource(source, transport, newClient)
+		if err != nil {
+			return nil, err
+		}
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+		httpClient = oauth2.NewClient(ctx, oauth2.ReuseTokenSource(nil, ts))
+	case source.TokenEnv != "":
+		token := os.Getenv(source.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("github token env var not set: %s", source.TokenEnv)
+		}
+		httpClient = newGitHubTokenClient(token, transport)
+	case source.Netrc:
+		login, password, err := lookupNetrc(apiURL.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		httpClient = (&github.BasicAuthTransport{Username: login, Password: password, Transport: transport}).Client()
+	case os.Getenv(util.GitHubTokenEnvVar) != "":
+		httpClient = newGitHubTokenClient(os.Getenv(util.GitHubTokenEnvVar), transport)
+	default:
+		httpClient = &http.Client{Transport: transport}
+	}
+
+	return newClient(httpClient)
+}
+
+func newGitHubTokenClient(token string, transport http.RoundTripper) *http.Client {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	return oauth2.NewClient(ctx, ts)
+}
+
+// gitHubRateLimitTransport logs the rate limit and retries the requests
+// hitting a secondary rate limit.
+type gitHubRateLimitTransport struct {
+	log   zerolog.Logger
+	base  http.RoundTripper
+	sleep func(time.Duration)
+}
+
+func (t *gitHubRateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait := time.Second
+	for retry := 0; ; retry++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != "" {
+			t.log.Debug().Str("remaining", remaining).
+				Str("limit", resp.Header.Get("X-RateLimit-Limit")).
+				Msg("github rate limit")
+			if value, err := strconv.ParseFloat(remaining, 64); err == nil {
+				metrics.GitHubRateLimitRemaining.Set(value, req.URL.Hostname())
+			}
+		}
+
+		retryAfter, limited := isGitHubSecondaryRateLimit(resp)
+		if !limited || retry == gitHubMaxRetries || req.Body != nil {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		if wait > gitHubMaxRetryWait {
+			wait = gitHubMaxRetryWait
+		}
+		t.log.Warn().Dur("wait", wait).
+			Int("retry", retry+1).
+			Msg("github secondary rate limit hit, backing off")
+		t.sleep(wait)
+		wait *= 2
+	}
+}
+
+// isGitHubSecondaryRateLimit returns true if the response is a secondary,
+// or abuse, rate limit error and the delay requested by the server.
+func isGitHubSecondaryRateLimit(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+
+	// The primary rate limit is exhausted until the reset, no retry.
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return 0, false
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 0, false
+	}
+
+	message := strings.ToLower(string(body))
+	if retryAfter > 0 || strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse") {
+		return retryAfter, true
+	}
+	return 0, false
+}
+
+// gitHubAppTokenSource returns installation tokens of a GitHub App.
+type gitHubAppTokenSource struct {
+	appID          int64
+	installationID int64
+	owner          string
+	repository     string
+	// client is authenticated as the app with a JWT.
+	client *github.Client
+}
+
+func newGitHubAppTokenSource(source *core.GitHubSource, transport http.RoundTripper,
+	newClient func(*http.Client) (*github.Client, error)) (*gitHubAppTokenSource, error) {
+
+	data, err := ioutil.ReadFile(source.App.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseRSAPrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtClient := &http.Client{Transport: &gitHubAppJWTTransport{
+		appID: source.App.AppID,
+		key:   key,
+		base:  transport,
+	}}
+	client, err := newClient(jwtClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gitHubAppTokenSource{
+		appID:          source.App.AppID,
+		installationID: source.App.InstallationID,
+		owner:          source.Owner,
+		repository:     source.Repository,
+		client:         client,
+	}, nil
+}
+
+// Token exchanges the JWT of the app for an installation token, the
+// installation of the repository is used by default.
+func (s *gitHubAppTokenSource) Token() (*oauth2.Token, error) {
+	ctx := context.Background()
+
+	if s.installationID == 0 {
+		installation, _, err := s.client.Apps.FindRepositoryInstallation(ctx, s.owner, s.repository)
+		if err != nil {
+			return nil, err
+		}
+		s.installationID = installation.GetID()
+	}
+
+	token, _, err := s.client.Apps.CreateInstallationToken(ctx, s.installationID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "token",
+		Expiry:      token.GetExpiresAt(),
+	}, nil
+}
+
+// gitHubAppJWTTransport authenticates the requests as a GitHub App.
+type gitHubAppJWTTransport struct {
+	appID int64
+	key   *rsa.PrivateKey
+	base  http.RoundTripper
+}
+
+func (t *gitHubAppJWTTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := newGitHubAppJWT(t.appID, t.key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrip must not modify the request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return t.base.RoundTrip(req)
+}
+
+// newGitHubAppJWT returns a JWT signed with RS256 as required by the apps.
+func newGitHubAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		// issued in the past to allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(gitHubAppJWTLifetime).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey parses a PEM encoded PKCS#1 or PKCS#8 RSA key.
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not a RSA key")
+	}
+	return rsaKey, nil
+}
+
+// lookupNetrc returns the credentials of the host from $NETRC or ~/.netrc,
+// the credentials of github.com are used for api.github.com.
+func lookupNetrc(host string) (string, string, error) {
+	path := os.Getenv(netrcEnvVar)
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", err
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	if login, password, ok := parseNetrc(data, host, strings.TrimPrefix(host, "api.")); ok {
+		return login, password, nil
+	}
+	return "", "", fmt.Errorf("no credentials found in netrc for host: %s", host)
+}
+
+// parseNetrc returns the credentials of the first host found in the
+// machines of a netrc file, or of the default entry.
+func parseNetrc(data []byte, hosts ...string) (string, string, bool) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Split(bufio.ScanWords)
+
+	var fields []string
+	for sc.Scan() {
+		fields = append(fields, sc.Text())
+	}
+
+	type entry struct{ login, password string }
+	machines := map[string]*entry{}
+	var def, current *entry
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			current = nil
+			if i+1 < len(fields) {
+				i++
+				if _, ok := machines[fields[i]]; !ok {
+					current = &entry{}
+					machines[fields[i]] = current
+				}
+			}
+		case "default":
+			def = &entry{}
+			current = def
+		case "login", "password", "account":
+			if i+1 >= len(fields) {
+				break
+			}
+			i++
+			if current == nil {
+				continue
+			}
+			switch fields[i-1] {
+			case "login":
+				current.login = fields[i]
+			case "password":
+				current.password = fields[i]
+			}
+		case "macdef":
+			// the macros run until an empty line and end the entries
+			// that can be parsed word by word.
+			i = len(fields)
+		}
+	}
+
+	found := def
+	for i := len(hosts) - 1; i >= 0; i-- {
+		if e, ok := machines[hosts[i]]; ok {
+			found = e
+		}
+	}
+	if found == nil || found.password == "" {
+		return "", "", false
+	}
+	return found.login, found.password, true
+}
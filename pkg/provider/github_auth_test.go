@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rs/zerolog"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/metrics"
+)
+
+var _ = Describe("GitHub Auth", func() {
+	It("signs the JWT of an app", func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(BeNil())
+
+		now := time.Unix(1600000000, 0)
+		jwt, err := newGitHubAppJWT(42, key, now)
+		Expect(err).To(BeNil())
+
+		parts := strings.Split(jwt, ".")
+		Expect(parts).To(HaveLen(3))
+
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		Expect(err).To(BeNil())
+		var c map[string]int64
+		Expect(json.Unmarshal(claims, &c)).To(Succeed())
+		Expect(c["iss"]).To(Equal(int64(42)))
+		Expect(c["iat"]).To(Equal(now.Add(-time.Minute).Unix()))
+		Expect(c["exp"]).To(Equal(now.Add(gitHubAppJWTLifetime).Unix()))
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		Expect(err).To(BeNil())
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		Expect(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature)).To(Succeed())
+	})
+	It("exchanges the JWT for an installation token", func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(BeNil())
+		dir, err := ioutil.TempDir("", "gojo-github")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+		keyFile := filepath.Join(dir, "app.pem")
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		Expect(ioutil.WriteFile(keyFile, keyPEM, 0600)).To(Succeed())
+
+		var tokens int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := r.Header.Get("Authorization")
+			switch r.URL.Path {
+			case "/api/v3/repos/owner/repo/installation":
+				Expect(auth).To(HavePrefix("Bearer "))
+				_, _ = w.Write([]byte(`{"id": 7}`))
+			case "/api/v3/app/installations/7/access_tokens":
+				Expect(auth).To(HavePrefix("Bearer "))
+				tokens++
+				expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+				_, _ = w.Write([]byte(`{"token": "installation-token", "expires_at": "` + expiry + `"}`))
+			case "/api/v3/repos/owner/repo/tags":
+				Expect(auth).To(Equal("token installation-token"))
+				_, _ = w.Write([]byte(`[{"name": "v1.0.0"}]`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		g, err := NewGitHub(&core.GitHubSource{
+			Owner:      "owner",
+			Repository: "repo",
+			Object:     core.GitHubObjectTag,
+			Limit:      gitHubDefaultLimit,
+			BaseURL:    server.URL + "/",
+			App:        &core.GitHubAppAuth{AppID: 42, PrivateKeyFile: keyFile},
+		})
+		Expect(err).To(BeNil())
+
+		for i := 0; i < 2; i++ {
+			value, err := g.GetFact(&core.Fact{Kind: core.VersionFactKind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("v1.0.0"))
+		}
+		// the token is reused until it expires
+		Expect(tokens).To(Equal(1))
+	})
+	It("reads the credentials from netrc", func() {
+		data := []byte(`machine example.com login other password secret
+default login anonymous password guest
+machine github.com
+  login user
+  password ghp_token
+`)
+		login, password, ok := parseNetrc(data, "github.com")
+		Expect(ok).To(BeTrue())
+		Expect(login).To(Equal("user"))
+		Expect(password).To(Equal("ghp_token"))
+
+		login, _, ok = parseNetrc(data, "unknown.com")
+		Expect(ok).To(BeTrue())
+		Expect(login).To(Equal("anonymous"))
+
+		dir, err := ioutil.TempDir("", "gojo-netrc")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+		netrc := filepath.Join(dir, "netrc")
+		Expect(ioutil.WriteFile(netrc, data, 0600)).To(Succeed())
+		os.Setenv(netrcEnvVar, netrc)
+		defer os.Unsetenv(netrcEnvVar)
+
+		login, _, err = lookupNetrc("api.github.com")
+		Expect(err).To(BeNil())
+		Expect(login).To(Equal("user"))
+	})
+	It("backs off on secondary rate limits", func() {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("X-RateLimit-Remaining", "4000")
+			if requests < 3 {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+				return
+			}
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		defer server.Close()
+
+		var waits []time.Duration
+		client := &http.Client{Transport: &gitHubRateLimitTransport{
+			log:   zerolog.Nop(),
+			base:  http.DefaultTransport,
+			sleep: func(d time.Duration) { waits = append(waits, d) },
+		}}
+		resp, err := client.Get(server.URL)
+		Expect(err).To(BeNil())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(waits).To(Equal([]time.Duration{time.Second, 2 * time.Second}))
+
+		var buf bytes.Buffer
+		Expect(metrics.Default.Write(&buf)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring(`gojo_github_rate_limit_remaining{host="127.0.0.1"} 4000`))
+	})
+})
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var _ = Describe("GitHub Provider", func() {
+	var server *httptest.Server
+	var requests int
+
+	// pages are served in order, the newest versions first like the API.
+	pages := [][]string{
+		{"v2.0.0-rc.1", "v1.19.2", "latest"},
+		{"v1.20.0", "v1.18.5"},
+		{"v1.19.1", "v1.18.4"},
+	}
+
+	BeforeEach(func() {
+		requests = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			if page < len(pages) {
+				w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d>; rel="next"`, server.URL, r.URL.Path, page+1))
+			}
+
+			switch r.URL.Path {
+			case "/repos/owner/repo/releases/tags/v1.19.2":
+				_, _ = w.Write([]byte(`{"tag_name": "v1.19.2", "body": "Bug fixes\n", "assets": [
+					{"id": 1, "name": "app_1.19.2_linux_amd64.tar.gz", "browser_download_url": "https://github.com/owner/repo/releases/download/v1.19.2/app_1.19.2_linux_amd64.tar.gz"},
+					{"id": 2, "name": "checksums.txt"}]}`))
+				return
+			case "/repos/owner/repo/compare/v1.19.1...v1.19.2":
+				_, _ = w.Write([]byte(`{"html_url": "https://github.com/owner/repo/compare/v1.19.1...v1.19.2"}`))
+				return
+			case "/repos/owner/repo/releases/assets/1":
+				_, _ = w.Write([]byte("asset"))
+				return
+			case "/repos/owner/repo/releases/assets/2":
+				_, _ = w.Write([]byte("d59386e0ae435e292fbe0ebcdb954b75ed5fb3922091277cb19f798fc5d50718  app_1.19.2_linux_amd64.tar.gz\n"))
+				return
+			}
+
+			var items []map[string]interface{}
+			for _, name := range pages[page-1] {
+				switch r.URL.Path {
+				case "/repos/owner/repo/releases":
+					items = append(items, map[string]interface{}{"tag_name": name, "prerelease": name == "v1.20.0"})
+				case "/repos/owner/repo/tags":
+					items = append(items, map[string]interface{}{"name": name})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(items)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newGitHub := func(object core.GitHubObject, limit int) *GitHub {
+		g, err := NewGitHub(&core.GitHubSource{Owner: "owner", Repository: "repo", Object: object, Limit: limit})
+		Expect(err).To(BeNil())
+		g.client.BaseURL, _ = url.Parse(server.URL + "/")
+		return g
+	}
+
+	It("pages through the tags and sorts them", func() {
+		g := newGitHub(core.GitHubObjectTag, gitHubDefaultLimit)
+		v, err := g.GetAll()
+		Expect(err).To(BeNil())
+		Expect(requests).To(Equal(3))
+		Expect(v.stable).To(Equal([]string{"v1.20.0", "v1.19.2", "v1.19.1", "v1.18.5", "v1.18.4"}))
+		Expect(v.unstable).To(Equal([]string{"v2.0.0-rc.1"}))
+		Expect(v.invalid).To(Equal([]string{"latest"}))
+
+		value, err := g.GetFact(&core.Fact{Kind: core.VersionFactKind, Semver: ">=1.18.0 <1.19.0"})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("v1.18.5"))
+	})
+	It("keeps the releases flagged as prerelease unstable", func() {
+		g := newGitHub(core.GitHubObjectRelease, gitHubDefaultLimit)
+		value, err := g.GetFact(&core.Fact{Kind: core.VersionFactKind})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("v1.19.2"))
+	})
+	It("returns the URL and the SHA256 of a release asset", func() {
+		g := newGitHub(core.GitHubObjectRelease, gitHubDefaultLimit)
+		cacheDir, err := ioutil.TempDir("", "gojo-assets")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(cacheDir)
+		g.cacheDir = cacheDir
+		fact := &core.Fact{Kind: core.AssetURLFactKind, Asset: "app_{{ .Version }}_linux_amd64.tar.gz"}
+		value, err := g.GetFact(fact)
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("https://github.com/owner/repo/releases/download/v1.19.2/app_1.19.2_linux_amd64.tar.gz"))
+
+		// the asset is downloaded without checksums asset.
+		fact.Kind = core.AssetSHA256FactKind
+		value, err = g.GetFact(fact)
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("d59386e0ae435e292fbe0ebcdb954b75ed5fb3922091277cb19f798fc5d50718"))
+
+		g.checksums = "checksums.txt"
+		g.cacheDir = ""
+		value, err = g.GetFact(fact)
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("d59386e0ae435e292fbe0ebcdb954b75ed5fb3922091277cb19f798fc5d50718"))
+	})
+	It("returns the changelog of a version", func() {
+		g := newGitHub(core.GitHubObjectRelease, gitHubDefaultLimit)
+		changelog, err := g.GetChangelog("v1.19.1", "v1.19.2")
+		Expect(err).To(BeNil())
+		Expect(changelog.Notes).To(Equal("Bug fixes"))
+		Expect(changelog.CompareURL).To(Equal("https://github.com/owner/repo/compare/v1.19.1...v1.19.2"))
+
+		// the tags without release have no notes.
+		changelog, err = g.GetChangelog("", "v1.18.5")
+		Expect(err).To(BeNil())
+		Expect(changelog).To(Equal(&Changelog{}))
+	})
+	It("stops at the limit", func() {
+		g := newGitHub(core.GitHubObjectTag, 4)
+		v, err := g.GetAll()
+		Expect(err).To(BeNil())
+		Expect(requests).To(Equal(2))
+		Expect(v.stable).To(Equal([]string{"v1.20.0", "v1.19.2"}))
+	})
+})
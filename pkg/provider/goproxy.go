@@ -0,0 +1,215 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+const (
+	goProxyDefault = "https://proxy.golang.org"
+)
+
+type GoProxy struct {
+	filtered
+
+	log zerolog.Logger
+
+	proxy  string
+	module string
+
+	// versions caches the releases of the module.
+	versions *Versions
+}
+
+func NewGoProxy(proxy, module string) *GoProxy {
+	return &GoProxy{
+		log:    log.With().Str("provider", string(ProviderGoProxy)).Logger(),
+		proxy:  strings.TrimSuffix(proxy, "/"),
+		module: module,
+	}
+}
+
+// escapeModulePath escapes the upper case letters of a module path,
+// e.g github.com/BurntSushi/toml is github.com/!burnt!sushi/toml.
+func escapeModulePath(module string) string {
+	var b strings.Builder
+	for _, r := range module {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (g *GoProxy) get(file string) ([]byte, error) {
+	u := fmt.Sprintf("%s/%s/@v/%s", g.proxy, escapeModulePath(g.module), file)
+
+	resp, err := http.DefaultClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error getting go module file: %d, url=%s", resp.StatusCode, u)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (g *GoProxy) GetAll() (*Versions, error) {
+	if g.versions != nil {
+		return g.versions, nil
+	}
+
+	list, err := g.get("list")
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []registryRelease
+	for _, version := range strings.Fields(string(list)) {
+		releases = append(releases, registryRelease{version: version})
+	}
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("no versions found for module: %s", g.module)
+	}
+
+	// The retractions are declared in the go.mod of the latest version.
+	all := splitReleases(zerolog.Nop(), releases, parseSanitizedSemver)
+	if latest := append(all.stable, all.unstable...); len(latest) > 0 {
+		mod, err := g.get(latest[0] + ".mod")
+		if err != nil {
+			return nil, err
+		}
+		if isGoModDeprecated(mod) {
+			g.log.Warn().Str("module", g.module).Msg("module is deprecated")
+		}
+
+		retractions := parseGoModRetractions(mod)
+		for i, r := range releases {
+			releases[i].withdrawn = retractions.contains(r.version)
+		}
+	}
+
+	g.versions = splitReleases(g.log, g.filter.releases(g.log, releases), g.filter.parser(parseSanitizedSemver))
+	return g.versions, nil
+}
+
+func (g *GoProxy) GetFact(fact *core.Fact) (string, error) {
+	if err := checkSemverScheme(ProviderGoProxy, fact); err != nil {
+		return "", err
+	}
+
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		v, err := g.GetAll()
+		if err != nil {
+			return "", err
+		}
+		version, err := selectLatestVersion(v, fact.Semver, g.filter.parser(parseSanitizedSemver))
+		if err != nil {
+			return "", err
+		}
+		g.log.Info().Str("version", version).
+			Str("semver", fact.Semver).
+			Msg("version found")
+		return version, nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderGoProxy, fact.Kind)
+}
+
+// goRetractions are the version intervals retracted by a go.mod.
+type goRetractions [][2]string
+
+func (r goRetractions) contains(version string) bool {
+	v, err := parseSanitizedSemver(version)
+	if err != nil {
+		return false
+	}
+	for _, interval := range r {
+		low, lowErr := parseSanitizedSemver(interval[0])
+		high, highErr := parseSanitizedSemver(interval[1])
+		if lowErr != nil || highErr != nil {
+			continue
+		}
+		if v.GTE(low) && v.LTE(high) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGoModRetractions parses the retract directives of a go.mod, e.g
+// "retract v1.0.0", "retract [v1.0.0, v1.0.5]" or a retract block.
+func parseGoModRetractions(mod []byte) goRetractions {
+	var retractions goRetractions
+
+	addRetraction := func(s string) {
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+			bounds := strings.Split(strings.Trim(s, "[]"), ",")
+			if len(bounds) == 2 {
+				retractions = append(retractions, [2]string{strings.TrimSpace(bounds[0]), strings.TrimSpace(bounds[1])})
+			}
+			return
+		}
+		if s != "" {
+			retractions = append(retractions, [2]string{s, s})
+		}
+	}
+
+	inBlock := false
+	sc := bufio.NewScanner(bytes.NewReader(mod))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock:
+			addRetraction(line)
+		case strings.HasPrefix(line, "retract"):
+			directive := strings.TrimSpace(strings.TrimPrefix(line, "retract"))
+			if directive == "(" {
+				inBlock = true
+				continue
+			}
+			addRetraction(directive)
+		}
+	}
+
+	return retractions
+}
+
+// isGoModDeprecated returns true if the module has a deprecation comment.
+func isGoModDeprecated(mod []byte) bool {
+	sc := bufio.NewScanner(bytes.NewReader(mod))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "// Deprecated:") {
+			return true
+		}
+		if strings.HasPrefix(line, "module") {
+			return strings.Contains(line, "// Deprecated:")
+		}
+	}
+	return false
+}
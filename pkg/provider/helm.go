@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+const (
+	helmIndexFilename = "index.yaml"
+)
+
+type Helm struct {
+	filtered
+
+	log zerolog.Logger
+
+	repository string
+	chart      string
+	field      core.HelmChartField
+
+	// entries caches the versions of the chart.
+	entries []helmChartVersion
+}
+
+// helmIndex is the index.yaml of a chart repository.
+type helmIndex struct {
+	Entries map[string][]helmChartVersion `yaml:"entries"`
+}
+
+type helmChartVersion struct {
+	Version    string `yaml:"version"`
+	AppVersion string `yaml:"appVersion"`
+	Deprecated bool   `yaml:"deprecated"`
+	Digest     string `yaml:"digest"`
+}
+
+func NewHelm(repository, chart string, field core.HelmChartField) *Helm {
+	return &Helm{
+		log:        log.With().Str("provider", string(ProviderHelm)).Logger(),
+		repository: strings.TrimSuffix(repository, "/"),
+		chart:      chart,
+		field:      field,
+	}
+}
+
+// getEntries returns the versions of the chart which are not deprecated,
+// sorted by chart version highest first.
+func (h *Helm) getEntries() ([]helmChartVersion, error) {
+	if h.entries != nil {
+		return h.entries, nil
+	}
+
+	indexURL := fmt.Sprintf("%s/%s", h.repository, helmIndexFilename)
+	resp, err := http.DefaultClient.Get(indexURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("error getting helm repository index: %d, url=%s", resp.StatusCode, indexURL)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := parseHelmIndex(data, h.chart)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.Deprecated {
+			h.log.Debug().Str("version", e.Version).Msg("deprecated chart version ignored")
+			continue
+		}
+		h.entries = append(h.entries, e)
+	}
+	if len(h.entries) == 0 {
+		return nil, fmt.Errorf("no chart version found which is not deprecated: %s", h.chart)
+	}
+
+	return h.entries, nil
+}
+
+func parseHelmIndex(data []byte, chart string) ([]helmChartVersion, error) {
+	var index helmIndex
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+
+	entries, ok := index.Entries[chart]
+	if !ok {
+		return nil, fmt.Errorf("chart not found: %s", chart)
+	}
+
+	sorted := make([]helmChartVersion, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		vi, errI := semver.ParseTolerant(sorted[i].Version)
+		vj, errJ := semver.ParseTolerant(sorted[j].Version)
+		if errI != nil || errJ != nil {
+			// the chart versions which can't be parsed come last.
+			return errJ != nil && errI == nil
+		}
+		return vi.GT(vj)
+	})
+
+	return sorted, nil
+}
+
+// getLatestChartVersion returns the highest stable chart version whose
+// field satisfies the semver range.
+func (h *Helm) getLatestChartVersion(semverRange string) (*helmChartVersion, error) {
+	entries, err := h.getEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	expectedRange, err := parseVersionRange(core.SemverVersionScheme, semverRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for i, e := range entries {
+		chartVersion, err := semver.ParseTolerant(e.Version)
+		if err != nil || len(chartVersion.Pre) > 0 {
+			continue
+		}
+
+		value := h.fieldValue(&e)
+		if !h.filter.Match(value) {
+			continue
+		}
+		versions = append(versions, value)
+
+		ok, err := expectedRange(util.SanitizeVersion(h.filter.Transform(value)))
+		if err != nil {
+			h.log.Warn().Str("version", value).
+				Err(err).
+				Msg("parsing version failed")
+			continue
+		}
+		if ok {
+			h.log.Info().Str("version", e.Version).
+				Str("appVersion", e.AppVersion).
+				Str("semver", semverRange).
+				Msg("chart version found")
+			return &entries[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no version found matching semver, versions=%s, semver='%s'", strings.Join(versions, ","), semverRange)
+}
+
+func (h *Helm) fieldValue(e *helmChartVersion) string {
+	if h.field == core.HelmChartAppVersion {
+		return e.AppVersion
+	}
+	return e.Version
+}
+
+func (h *Helm) GetFact(fact *core.Fact) (string, error) {
+	if err := checkSemverScheme(ProviderHelm, fact); err != nil {
+		return "", err
+	}
+
+	switch h.field {
+	case core.HelmChartVersion, core.HelmChartAppVersion:
+	default:
+		return "", fmt.Errorf("helm chart field not recognized: %s", h.field)
+	}
+
+	e, err := h.getLatestChartVersion(fact.Semver)
+	if err != nil {
+		return "", err
+	}
+
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		return h.fieldValue(e), nil
+	case core.ChecksumFactKind:
+		return e.Digest, nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderHelm, fact.Kind)
+}
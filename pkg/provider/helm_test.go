@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var _ = Describe("Helm Provider", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		index, err := ioutil.ReadFile("testdata/helm-index.yaml")
+		Expect(err).To(BeNil())
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/charts/index.yaml" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(index)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("sorts the chart versions", func() {
+		index, err := ioutil.ReadFile("testdata/helm-index.yaml")
+		Expect(err).To(BeNil())
+		entries, err := parseHelmIndex(index, "ingress-nginx")
+		Expect(err).To(BeNil())
+		var versions []string
+		for _, e := range entries {
+			versions = append(versions, e.Version)
+		}
+		Expect(versions).To(Equal([]string{"4.1.0-beta.0", "4.0.15", "4.0.13", "4.0.10"}))
+
+		_, err = parseHelmIndex(index, "unknown")
+		Expect(err).To(HaveOccurred())
+	})
+	It("returns the latest chart version skipping the deprecated ones", func() {
+		h := NewHelm(server.URL+"/charts/", "ingress-nginx", core.HelmChartVersion)
+		value, err := h.GetFact(&core.Fact{Kind: core.VersionFactKind})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("4.0.13"))
+
+		value, err = h.GetFact(&core.Fact{Kind: core.ChecksumFactKind})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("9d3e5f1a7b2c4d6e8f0a1b3c5d7e9f1a2b4c6d8e0f1a3b5c7d9e1f2a4b6c8d0e"))
+	})
+	It("applies the semver range to the app version", func() {
+		h := NewHelm(server.URL+"/charts", "ingress-nginx", core.HelmChartAppVersion)
+		value, err := h.GetFact(&core.Fact{Kind: core.VersionFactKind, Semver: "<1.1.0"})
+		Expect(err).To(BeNil())
+		Expect(value).To(Equal("v1.0.5"))
+	})
+	It("fails because of an unknown field", func() {
+		h := NewHelm(server.URL+"/charts", "ingress-nginx", "name")
+		_, err := h.GetFact(&core.Fact{Kind: core.VersionFactKind})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,253 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+type HTTP struct {
+	filtered
+
+	log zerolog.Logger
+
+	url       string
+	headers   map[string]string
+	basicAuth *core.HTTPBasicAuth
+	extract   extractor
+	// checksums is the template of the URL of the checksums file.
+	checksums string
+	// cacheDir caches the checksums of the downloaded assets.
+	cacheDir string
+
+	// candidates caches the values extracted from the document.
+	candidates []string
+}
+
+func NewHTTP(source *core.HTTPSource) (*HTTP, error) {
+	h := &HTTP{
+		log:       log.With().Str("provider", string(ProviderHTTP)).Logger(),
+		url:       source.URL,
+		headers:   source.Headers,
+		basicAuth: source.BasicAuth,
+		checksums: source.Checksums,
+		cacheDir:  DefaultAssetCacheDir(),
+	}
+
+	var err error
+	switch {
+	case source.JSONPath != "":
+		h.extract, err = newJSONPathExtractor(source.JSONPath)
+	case source.XPath != "":
+		h.extract, err = newXPathExtractor(source.XPath)
+	case source.Regex != "":
+		h.extract, err = newRegexExtractor(source.Regex)
+	default:
+		// The document is the value, e.g stable.txt.
+		h.extract = func(data []byte) ([]string, error) {
+			return []string{strings.TrimSpace(string(data))}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (h *HTTP) newRequest(u string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The values are expanded so the secrets come from the environment,
+	// e.g "Bearer ${API_TOKEN}".
+	for k, v := range h.headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+
+	if h.basicAuth != nil {
+		username, ok := os.LookupEnv(h.basicAuth.UsernameEnv)
+		if !ok {
+			return nil, fmt.Errorf("basic auth username env var not set: %s", h.basicAuth.UsernameEnv)
+		}
+		password, ok := os.LookupEnv(h.basicAuth.PasswordEnv)
+		if !ok {
+			return nil, fmt.Errorf("basic auth password env var not set: %s", h.basicAuth.PasswordEnv)
+		}
+		req.SetBasicAuth(username, password)
+	}
+
+	return req, nil
+}
+
+// get returns the body of the document at u, requested with the headers
+// and the credentials of the source.
+func (h *HTTP) get(u string) (io.ReadCloser, error) {
+	req, err := h.newRequest(u)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error getting http document: %d, url=%s", resp.StatusCode, u)
+	}
+
+	return resp.Body, nil
+}
+
+// getCandidates returns the values extracted from the document, in the
+// document order.
+func (h *HTTP) getCandidates() ([]string, error) {
+	if h.candidates != nil {
+		return h.candidates, nil
+	}
+
+	body, err := h.get(h.url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := h.extract(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			h.candidates = append(h.candidates, v)
+		}
+	}
+	if len(h.candidates) == 0 {
+		return nil, fmt.Errorf("no value extracted from document, url=%s", h.url)
+	}
+
+	h.log.Debug().Str("candidates", strings.Join(h.candidates, ",")).
+		Msg("values extracted")
+
+	return h.candidates, nil
+}
+
+func (h *HTTP) GetFact(fact *core.Fact) (string, error) {
+	if err := checkSemverScheme(ProviderHTTP, fact); err != nil {
+		return "", err
+	}
+
+	candidates, err := h.getCandidates()
+	if err != nil {
+		return "", err
+	}
+
+	var releases []registryRelease
+	for _, c := range candidates {
+		releases = append(releases, registryRelease{version: c})
+	}
+	releases = h.filter.releases(h.log, releases)
+	if len(releases) == 0 {
+		return "", fmt.Errorf("no extracted value selected by the filter, url=%s", h.url)
+	}
+
+	switch fact.Kind {
+	case core.StringFactKind:
+		// A string is not necessarily a version, e.g a commit.
+		if fact.Semver == "" {
+			return releases[0].version, nil
+		}
+		fallthrough
+	case core.VersionFactKind:
+		return h.selectLatestVersion(releases, fact.Semver)
+	case core.AssetURLFactKind, core.AssetSHA256FactKind:
+		version, err := h.selectLatestVersion(releases, fact.Semver)
+		if err != nil {
+			return "", err
+		}
+		return h.getAssetFact(fact, version)
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderHTTP, fact.Kind)
+}
+
+func (h *HTTP) selectLatestVersion(releases []registryRelease, semverRange string) (string, error) {
+	v := splitReleases(h.log, releases, h.filter.parser(parseSanitizedSemver))
+	version, err := selectLatestVersion(v, semverRange, h.filter.parser(parseSanitizedSemver))
+	if err != nil {
+		return "", err
+	}
+	h.log.Info().Str("version", version).
+		Str("semver", semverRange).
+		Msg("version found")
+	return version, nil
+}
+
+// getAssetFact returns the URL or the SHA256 of the asset of the version,
+// the asset of the fact is the URL template.
+func (h *HTTP) getAssetFact(fact *core.Fact, version string) (string, error) {
+	v := newAssetVersion(h.filter, version)
+	assetURL, err := renderAssetTemplate(fact.Asset, v)
+	if err != nil {
+		return "", err
+	}
+	if fact.Kind == core.AssetURLFactKind {
+		return assetURL, nil
+	}
+
+	var sum string
+	if h.checksums != "" {
+		checksumsURL, err := renderAssetTemplate(h.checksums, v)
+		if err != nil {
+			return "", err
+		}
+		body, err := h.get(checksumsURL)
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		u, err := url.Parse(assetURL)
+		if err != nil {
+			return "", err
+		}
+		if sum, err = parseChecksums(data, path.Base(u.Path)); err != nil {
+			return "", err
+		}
+	} else {
+		download := func() (io.ReadCloser, error) {
+			h.log.Info().Str("asset", assetURL).Msg("download asset to compute its checksum")
+			return h.get(assetURL)
+		}
+		if sum, err = cachedAssetSHA256(h.cacheDir, assetURL, download); err != nil {
+			return "", err
+		}
+	}
+
+	h.log.Info().Str("asset", assetURL).
+		Str("sha256", sum).
+		Msg("asset checksum found")
+	return sum, nil
+}
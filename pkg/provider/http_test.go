@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var _ = Describe("HTTP Provider", func() {
+	Describe("Extractors", func() {
+		It("extracts the values selected by a JSONPath", func() {
+			doc := []byte(`{"name": "app", "latest": {"version": "1.10"},
+				"items": [{"version": "v1.2.0"}, {"version": "v1.3.0", "meta": {"version": "nested"}}]}`)
+			tests := map[string][]string{
+				"$.latest.version":       {"1.10"},
+				"$['latest']['version']": {"1.10"},
+				"$.items[*].version":     {"v1.2.0", "v1.3.0"},
+				"$.items[-1].version":    {"v1.3.0"},
+				"$..version":             {"v1.2.0", "v1.3.0", "nested", "1.10"},
+				"$.unknown":              nil,
+			}
+			for expr, expected := range tests {
+				extract, err := newJSONPathExtractor(expr)
+				Expect(err).To(BeNil(), expr)
+				values, err := extract(doc)
+				Expect(err).To(BeNil(), expr)
+				Expect(values).To(Equal(expected), expr)
+			}
+			for _, expr := range []string{"latest.version", "$.", "$[abc]"} {
+				_, err := newJSONPathExtractor(expr)
+				Expect(err).To(HaveOccurred(), expr)
+			}
+		})
+		It("extracts the values selected by a XPath", func() {
+			doc := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry><title>v2.1.0</title><link href="https://example.com/v2.1.0"/></entry>
+  <entry><title>v2.0.0</title><link href="https://example.com/v2.0.0"/></entry>
+</feed>`)
+			tests := map[string][]string{
+				"/feed/entry/title":         {"v2.1.0", "v2.0.0"},
+				"//title/text()":            {"v2.1.0", "v2.0.0"},
+				"/feed/entry[2]/link/@href": {"https://example.com/v2.0.0"},
+				"/feed/*[1]/title":          {"v2.1.0"},
+				"/rss/channel/item/title":   nil,
+			}
+			for expr, expected := range tests {
+				extract, err := newXPathExtractor(expr)
+				Expect(err).To(BeNil(), expr)
+				values, err := extract(doc)
+				Expect(err).To(BeNil(), expr)
+				Expect(values).To(Equal(expected), expr)
+			}
+			for _, expr := range []string{"feed/entry", "/feed/entry[a]", "/"} {
+				_, err := newXPathExtractor(expr)
+				Expect(err).To(HaveOccurred(), expr)
+			}
+		})
+		It("extracts the first capture group of a regex", func() {
+			extract, err := newRegexExtractor(`nginx-(\d+\.\d+\.\d+)\.tar\.gz`)
+			Expect(err).To(BeNil())
+			values, err := extract([]byte(`<a href="nginx-1.20.2.tar.gz">nginx-1.20.2</a> <a href="nginx-1.21.6.tar.gz">`))
+			Expect(err).To(BeNil())
+			Expect(values).To(Equal([]string{"1.20.2", "1.21.6"}))
+
+			_, err = newRegexExtractor(`nginx-\d+`)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Get facts", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/stable.txt":
+					_, _ = w.Write([]byte("v1.23.1\n"))
+				case "/dl/app-1.23.1.tar.gz":
+					_, _ = w.Write([]byte("asset"))
+				case "/dl/v1.23.1/SHA256SUMS":
+					_, _ = w.Write([]byte("d59386e0ae435e292fbe0ebcdb954b75ed5fb3922091277cb19f798fc5d50718  ./app-1.23.1.tar.gz\n"))
+				case "/api/releases":
+					if user, password, ok := r.BasicAuth(); !ok || user != "gojo" || password != "secret" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					_, _ = w.Write([]byte(`[{"tag": "2.1.0"}, {"tag": "2.2.0-rc.1"}, {"tag": "2.0.3"}]`))
+				case "/api/latest":
+					if r.Header.Get("Authorization") != "Bearer token" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					_, _ = w.Write([]byte(`{"commit": "1a2b3c4d"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			os.Setenv("GOJO_TEST_USER", "gojo")
+			os.Setenv("GOJO_TEST_PASSWORD", "secret")
+			os.Setenv("GOJO_TEST_TOKEN", "token")
+		})
+		AfterEach(func() {
+			server.Close()
+			os.Unsetenv("GOJO_TEST_USER")
+			os.Unsetenv("GOJO_TEST_PASSWORD")
+			os.Unsetenv("GOJO_TEST_TOKEN")
+		})
+
+		It("returns the whole document without extractor", func() {
+			h, err := NewHTTP(&core.HTTPSource{URL: server.URL + "/stable.txt"})
+			Expect(err).To(BeNil())
+			value, err := h.GetFact(&core.Fact{Kind: core.VersionFactKind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("v1.23.1"))
+		})
+		It("filters the candidates with the semver range", func() {
+			h, err := NewHTTP(&core.HTTPSource{
+				URL:       server.URL + "/api/releases",
+				BasicAuth: &core.HTTPBasicAuth{UsernameEnv: "GOJO_TEST_USER", PasswordEnv: "GOJO_TEST_PASSWORD"},
+				JSONPath:  "$[*].tag",
+			})
+			Expect(err).To(BeNil())
+			value, err := h.GetFact(&core.Fact{Kind: core.VersionFactKind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("2.1.0"))
+
+			value, err = h.GetFact(&core.Fact{Kind: core.VersionFactKind, Semver: "<2.1.0"})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("2.0.3"))
+		})
+		It("returns a string which is not a version", func() {
+			h, err := NewHTTP(&core.HTTPSource{
+				URL:      server.URL + "/api/latest",
+				Headers:  map[string]string{"Authorization": "Bearer ${GOJO_TEST_TOKEN}"},
+				JSONPath: "$.commit",
+			})
+			Expect(err).To(BeNil())
+			value, err := h.GetFact(&core.Fact{Kind: core.StringFactKind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("1a2b3c4d"))
+		})
+		It("returns the URL and the SHA256 of an asset", func() {
+			h, err := NewHTTP(&core.HTTPSource{
+				URL:       server.URL + "/stable.txt",
+				Checksums: server.URL + "/dl/{{ .RawVersion }}/SHA256SUMS",
+			})
+			Expect(err).To(BeNil())
+			fact := &core.Fact{Kind: core.AssetURLFactKind, Asset: server.URL + "/dl/app-{{ .Version }}.tar.gz"}
+			value, err := h.GetFact(fact)
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal(server.URL + "/dl/app-1.23.1.tar.gz"))
+
+			fact.Kind = core.AssetSHA256FactKind
+			value, err = h.GetFact(fact)
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("d59386e0ae435e292fbe0ebcdb954b75ed5fb3922091277cb19f798fc5d50718"))
+
+			// without checksums file, the asset is downloaded.
+			h.checksums = ""
+			cacheDir, err := ioutil.TempDir("", "gojo-assets")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(cacheDir)
+			h.cacheDir = cacheDir
+			value, err = h.GetFact(fact)
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("d59386e0ae435e292fbe0ebcdb954b75ed5fb3922091277cb19f798fc5d50718"))
+		})
+		It("fails because the basic auth env var is not set", func() {
+			h, err := NewHTTP(&core.HTTPSource{
+				URL:       server.URL + "/api/releases",
+				BasicAuth: &core.HTTPBasicAuth{UsernameEnv: "GOJO_TEST_UNSET", PasswordEnv: "GOJO_TEST_PASSWORD"},
+			})
+			Expect(err).To(BeNil())
+			_, err = h.GetFact(&core.Fact{Kind: core.VersionFactKind})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
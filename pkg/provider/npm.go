@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+const (
+	npmDefaultRegistry = "https://registry.npmjs.org"
+	// npmAbbreviatedMetadata is the media type of the abbreviated package
+	// metadata, it is much smaller than the full document.
+	npmAbbreviatedMetadata = "application/vnd.npm.install-v1+json; q=1.0, application/json; q=0.8"
+)
+
+type Npm struct {
+	filtered
+
+	log zerolog.Logger
+
+	registry string
+	pkgName  string
+
+	// versions caches the releases of the package.
+	versions *Versions
+}
+
+// npmPackument is the package document returned by the npm registry.
+type npmPackument struct {
+	Versions map[string]struct {
+		// Deprecated is the deprecation message, some registries use
+		// a boolean.
+		Deprecated interface{} `json:"deprecated"`
+	} `json:"versions"`
+}
+
+func NewNpm(registry, pkgName string) *Npm {
+	return &Npm{
+		log:      log.With().Str("provider", string(ProviderNpm)).Logger(),
+		registry: strings.TrimSuffix(registry, "/"),
+		pkgName:  pkgName,
+	}
+}
+
+func isNpmDeprecated(deprecated interface{}) bool {
+	switch d := deprecated.(type) {
+	case string:
+		return d != ""
+	case bool:
+		return d
+	}
+	return false
+}
+
+func (n *Npm) GetAll() (*Versions, error) {
+	if n.versions != nil {
+		return n.versions, nil
+	}
+
+	// The slash of scoped packages is escaped, e.g @babel%2Fcore.
+	header := http.Header{"Accept": []string{npmAbbreviatedMetadata}}
+	var packument npmPackument
+	if err := getRegistryJSON(fmt.Sprintf("%s/%s", n.registry, url.PathEscape(n.pkgName)), header, &packument); err != nil {
+		return nil, err
+	}
+
+	var releases []registryRelease
+	for version, meta := range packument.Versions {
+		releases = append(releases, registryRelease{version: version, withdrawn: isNpmDeprecated(meta.Deprecated)})
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].version < releases[j].version })
+
+	n.versions = splitReleases(n.log, n.filter.releases(n.log, releases), n.filter.parser(parseSanitizedSemver))
+	return n.versions, nil
+}
+
+func (n *Npm) GetFact(fact *core.Fact) (string, error) {
+	if err := checkSemverScheme(ProviderNpm, fact); err != nil {
+		return "", err
+	}
+
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		v, err := n.GetAll()
+		if err != nil {
+			return "", err
+		}
+		version, err := selectLatestVersion(v, fact.Semver, n.filter.parser(parseSanitizedSemver))
+		if err != nil {
+			return "", err
+		}
+		n.log.Info().Str("version", version).
+			Str("semver", fact.Semver).
+			Msg("version found")
+		return version, nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderNpm, fact.Kind)
+}
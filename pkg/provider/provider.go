@@ -12,24 +12,130 @@ const (
 	defaultArch = "x86_64"
 )
 
-type provider interface {
-	GetLatest(semverRange string) (string, error)
+// Provider retrieves the facts of a source.
+type Provider interface {
+	// GetFact returns the latest value of a fact.
+	GetFact(fact *core.Fact) (string, error)
 }
 
-var _ provider = &Alpine{}
-var _ provider = &GitHub{}
+var _ Provider = &Alpine{}
+var _ Provider = &AlpineRelease{}
+var _ Provider = &Apt{}
+var _ Provider = &Rpm{}
+var _ Provider = &PyPI{}
+var _ Provider = &Npm{}
+var _ Provider = &Crates{}
+var _ Provider = &GoProxy{}
+var _ Provider = &Helm{}
+var _ Provider = &HTTP{}
+var _ Provider = &Git{}
+var _ Provider = &GitHub{}
 
-func New(pflagSet *pflag.FlagSet, source core.Source) (provider, error) {
+func New(pflagSet *pflag.FlagSet, source core.Source) (Provider, error) {
+	filter, err := NewVersionFilter(source)
+	if err != nil {
+		return nil, err
+	}
+
+	prvdr, err := newProvider(pflagSet, source)
+	if err != nil {
+		return nil, err
+	}
+	// The providers select their version among the candidates
+	// filtered and transformed by the filter of the source.
+	if f, ok := prvdr.(filterable); ok {
+		f.setFilter(filter)
+	}
+
+	return prvdr, nil
+}
+
+// SourceType returns the type of the provider of a source.
+func SourceType(source core.Source) ProviderType {
+	switch {
+	case source.Provider.Alpine != nil:
+		return ProviderAlpine
+	case source.Provider.AlpineRelease != nil:
+		return ProviderAlpineRelease
+	case source.Provider.Apt != nil:
+		return ProviderApt
+	case source.Provider.Rpm != nil:
+		return ProviderRpm
+	case source.Provider.PyPI != nil:
+		return ProviderPyPI
+	case source.Provider.Npm != nil:
+		return ProviderNpm
+	case source.Provider.Crates != nil:
+		return ProviderCrates
+	case source.Provider.GoProxy != nil:
+		return ProviderGoProxy
+	case source.Provider.Helm != nil:
+		return ProviderHelm
+	case source.Provider.HTTP != nil:
+		return ProviderHTTP
+	case source.Provider.Git != nil:
+		return ProviderGit
+	case source.Provider.GitHub != nil:
+		return ProviderGitHub
+	}
+	return ""
+}
+
+func newProvider(pflagSet *pflag.FlagSet, source core.Source) (Provider, error) {
 	switch {
 	case source.Provider.Alpine != nil:
 		a := source.Provider.Alpine
 		setDefaultsAlpine(a)
-		prvdr := NewAlpine(a.Mirror, a.Arch, a.VersionId, a.Repository, a.Package)
+		prvdr := NewAlpine(a.Mirror, a.GetArchs(), a.VersionId, a.GetRepositories(), a.Package)
+		return prvdr, nil
+	case source.Provider.AlpineRelease != nil:
+		a := source.Provider.AlpineRelease
+		setDefaultsAlpineRelease(a)
+		prvdr := NewAlpineRelease(a.Mirror, a.Branch, a.Arch, a.Flavor)
+		return prvdr, nil
+	case source.Provider.Apt != nil:
+		a := source.Provider.Apt
+		setDefaultsApt(a)
+		prvdr := NewApt(a.Mirror, a.Suite, a.Component, a.Arch, a.Package)
 		return prvdr, nil
+	case source.Provider.Rpm != nil:
+		r := source.Provider.Rpm
+		setDefaultsRpm(r)
+		prvdr := NewRpm(r.BaseURL, r.Arch, r.Package)
+		return prvdr, nil
+	case source.Provider.PyPI != nil:
+		p := source.Provider.PyPI
+		setDefaultsPyPI(p)
+		prvdr := NewPyPI(p.Index, p.Project)
+		return prvdr, nil
+	case source.Provider.Npm != nil:
+		n := source.Provider.Npm
+		setDefaultsNpm(n)
+		prvdr := NewNpm(n.Registry, n.Package)
+		return prvdr, nil
+	case source.Provider.Crates != nil:
+		c := source.Provider.Crates
+		setDefaultsCrates(c)
+		prvdr := NewCrates(c.API, c.Crate)
+		return prvdr, nil
+	case source.Provider.GoProxy != nil:
+		g := source.Provider.GoProxy
+		setDefaultsGoProxy(g)
+		prvdr := NewGoProxy(g.Proxy, g.Module)
+		return prvdr, nil
+	case source.Provider.Helm != nil:
+		h := source.Provider.Helm
+		setDefaultsHelm(h)
+		prvdr := NewHelm(h.Repository, h.Chart, h.Field)
+		return prvdr, nil
+	case source.Provider.HTTP != nil:
+		return NewHTTP(source.Provider.HTTP)
+	case source.Provider.Git != nil:
+		return NewGit(source.Provider.Git)
 	case source.Provider.GitHub != nil:
 		g := source.Provider.GitHub
-		prvdr := NewGitHub(g.Owner, g.Repository, g.Object)
-		return prvdr, nil
+		setDefaultsGitHub(g)
+		return NewGitHub(g)
 	}
 
 	return nil, fmt.Errorf("provider type not recognized: %s", source.Name)
@@ -39,7 +145,76 @@ func setDefaultsAlpine(repo *core.AlpineSource) {
 	if repo.Mirror == "" {
 		repo.Mirror = alpineDefaultMirror
 	}
+	if repo.Arch == "" && len(repo.Archs) == 0 {
+		repo.Arch = defaultArch
+	}
+}
+
+func setDefaultsAlpineRelease(release *core.AlpineReleaseSource) {
+	if release.Mirror == "" {
+		release.Mirror = alpineDefaultMirror
+	}
+	if release.Arch == "" {
+		release.Arch = defaultArch
+	}
+	if release.Branch == "" {
+		release.Branch = alpineReleaseDefaultBranch
+	}
+	if release.Flavor == "" {
+		release.Flavor = alpineReleaseDefaultFlavor
+	}
+}
+
+func setDefaultsApt(repo *core.AptSource) {
+	if repo.Mirror == "" {
+		repo.Mirror = aptDefaultMirror
+	}
+	if repo.Component == "" {
+		repo.Component = aptDefaultComponent
+	}
+	if repo.Arch == "" {
+		repo.Arch = aptDefaultArch
+	}
+}
+
+func setDefaultsRpm(repo *core.RpmSource) {
 	if repo.Arch == "" {
 		repo.Arch = defaultArch
 	}
 }
+
+func setDefaultsPyPI(project *core.PyPISource) {
+	if project.Index == "" {
+		project.Index = pypiDefaultIndex
+	}
+}
+
+func setDefaultsNpm(pkg *core.NpmSource) {
+	if pkg.Registry == "" {
+		pkg.Registry = npmDefaultRegistry
+	}
+}
+
+func setDefaultsCrates(crate *core.CratesSource) {
+	if crate.API == "" {
+		crate.API = cratesDefaultAPI
+	}
+}
+
+func setDefaultsGoProxy(module *core.GoProxySource) {
+	if module.Proxy == "" {
+		module.Proxy = goProxyDefault
+	}
+}
+
+func setDefaultsHelm(chart *core.HelmSource) {
+	if chart.Field == "" {
+		chart.Field = core.HelmChartVersion
+	}
+}
+
+func setDefaultsGitHub(repo *core.GitHubSource) {
+	if repo.Limit == 0 {
+		repo.Limit = gitHubDefaultLimit
+	}
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+const (
+	pypiDefaultIndex = "https://pypi.org/pypi"
+)
+
+type PyPI struct {
+	filtered
+
+	log zerolog.Logger
+
+	index   string
+	project string
+
+	// versions caches the releases of the project.
+	versions *Versions
+}
+
+// pypiProject is the document returned by the PyPI JSON API.
+type pypiProject struct {
+	Releases map[string][]struct {
+		Yanked bool `json:"yanked"`
+	} `json:"releases"`
+}
+
+func NewPyPI(index, project string) *PyPI {
+	return &PyPI{
+		log:     log.With().Str("provider", string(ProviderPyPI)).Logger(),
+		index:   strings.TrimSuffix(index, "/"),
+		project: project,
+	}
+}
+
+func (p *PyPI) GetAll() (*Versions, error) {
+	if p.versions != nil {
+		return p.versions, nil
+	}
+
+	// https://pypi.org/pypi/<project>/json
+	var project pypiProject
+	if err := getRegistryJSON(fmt.Sprintf("%s/%s/json", p.index, url.PathEscape(normalizePyPIProject(p.project))), nil, &project); err != nil {
+		return nil, err
+	}
+
+	var releases []registryRelease
+	for version, files := range project.Releases {
+		// A release is yanked when all its files are, a release without
+		// files can't be installed.
+		withdrawn := true
+		for _, f := range files {
+			if !f.Yanked {
+				withdrawn = false
+			}
+		}
+		releases = append(releases, registryRelease{version: version, withdrawn: withdrawn})
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].version < releases[j].version })
+
+	p.versions = splitReleases(p.log, p.filter.releases(p.log, releases), p.filter.parser(parsePEP440Version))
+	return p.versions, nil
+}
+
+func (p *PyPI) GetFact(fact *core.Fact) (string, error) {
+	if err := checkSemverScheme(ProviderPyPI, fact); err != nil {
+		return "", err
+	}
+
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		v, err := p.GetAll()
+		if err != nil {
+			return "", err
+		}
+		version, err := selectLatestVersion(v, fact.Semver, p.filter.parser(parsePEP440Version))
+		if err != nil {
+			return "", err
+		}
+		p.log.Info().Str("version", version).
+			Str("semver", fact.Semver).
+			Msg("version found")
+		return version, nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderPyPI, fact.Kind)
+}
+
+var pypiProjectSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizePyPIProject returns the normalized name of a project, see PEP
+// 503, e.g Zope.Interface is zope-interface.
+func normalizePyPIProject(name string) string {
+	return strings.ToLower(pypiProjectSeparators.ReplaceAllString(name, "-"))
+}
+
+var pep440Regexp = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)` +
+	`(?:[-_.]?(a|b|c|rc|alpha|beta|pre|preview)[-_.]?(\d*))?` +
+	`(?:-(\d+)|[-_.]?(post|rev|r)[-_.]?(\d*))?` +
+	`(?:[-_.]?(dev)[-_.]?(\d*))?$`)
+
+// pep440Phases normalizes the spellings of the prerelease phases.
+var pep440Phases = map[string]string{
+	"alpha":   "a",
+	"beta":    "b",
+	"c":       "rc",
+	"pre":     "rc",
+	"preview": "rc",
+}
+
+// parsePEP440Version converts a PEP 440 version to semver. The pre and
+// dev releases become prereleases, e.g 1.0rc1 is 1.0.0-rc.1, and the post
+// releases become build metadata, e.g 1.0.post1 is 1.0.0+post.1.
+//
+// PEP 440 orders the dev releases before the releases they lead to while
+// semver orders a prerelease before its extensions: 1.0.dev1 is
+// 1.0.0-0.dev.1, the numeric 0 preceding the phases, and the phases
+// without dev release end with final, e.g 1.0a1.dev1 is 1.0.0-a.1.dev.1
+// and 1.0a1 is 1.0.0-a.1.final.
+func parsePEP440Version(version string) (semver.Version, error) {
+	var v semver.Version
+
+	m := pep440Regexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(version)))
+	if m == nil {
+		return v, fmt.Errorf("invalid PEP 440 version: %s", version)
+	}
+
+	release := strings.Split(m[1], ".")
+	if len(release) > 3 {
+		return v, fmt.Errorf("PEP 440 version with more than 3 release segments: %s", version)
+	}
+	var numbers [3]uint64
+	for i, r := range release {
+		n, err := strconv.ParseUint(r, 10, 64)
+		if err != nil {
+			return v, err
+		}
+		numbers[i] = n
+	}
+	v = semver.Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}
+
+	// number drops the leading zeros, an implicit number is 0.
+	number := func(n string) string {
+		if n = strings.TrimLeft(n, "0"); n == "" {
+			return "0"
+		}
+		return n
+	}
+
+	var pre []string
+	switch phase := m[2]; {
+	case phase != "":
+		if normalized, ok := pep440Phases[phase]; ok {
+			phase = normalized
+		}
+		pre = append(pre, phase, number(m[3]))
+		if m[7] == "" {
+			pre = append(pre, "final")
+		}
+	case m[7] != "":
+		pre = append(pre, "0")
+	}
+	if m[7] != "" {
+		pre = append(pre, "dev", number(m[8]))
+	}
+	for _, p := range pre {
+		prVersion, err := semver.NewPRVersion(p)
+		if err != nil {
+			return v, err
+		}
+		v.Pre = append(v.Pre, prVersion)
+	}
+
+	switch {
+	case m[4] != "":
+		v.Build = []string{"post", number(m[4])}
+	case m[5] != "":
+		v.Build = []string{"post", number(m[6])}
+	}
+
+	return v, nil
+}
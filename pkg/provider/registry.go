@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+// registryUserAgent identifies the requests, crates.io rejects the
+// requests without User-Agent.
+const registryUserAgent = "gojo (https://github.com/spiarh/gojo)"
+
+// registryRelease is a release published on a package registry.
+type registryRelease struct {
+	version string
+	// withdrawn is true for the yanked, deprecated or retracted releases.
+	withdrawn bool
+	// prerelease is true if the registry flags the release as unstable
+	// whatever its version.
+	prerelease bool
+}
+
+// versionParser parses a version of a registry as a semver version.
+type versionParser func(version string) (semver.Version, error)
+
+func parseSanitizedSemver(version string) (semver.Version, error) {
+	return semver.Parse(util.SanitizeVersion(version))
+}
+
+// splitReleases splits the releases in stable and unstable versions, the
+// prereleases are unstable. The versions that can't be parsed are
+// reported as invalid, the withdrawn releases are dropped and the
+// versions are sorted highest first.
+func splitReleases(logger zerolog.Logger, releases []registryRelease, parse versionParser) *Versions {
+	type parsedVersion struct {
+		version string
+		semver  semver.Version
+	}
+
+	var stable, unstable []parsedVersion
+	var invalid []string
+	for _, r := range releases {
+		if r.withdrawn {
+			logger.Debug().Str("version", r.version).Msg("withdrawn release ignored")
+			continue
+		}
+		ver, err := parse(r.version)
+		if err != nil {
+			logger.Debug().
+				Str("version", r.version).
+				Err(err).
+				Msg("parsing version failed")
+			invalid = append(invalid, r.version)
+			continue
+		}
+		if r.prerelease || len(ver.Pre) > 0 {
+			unstable = append(unstable, parsedVersion{version: r.version, semver: ver})
+			continue
+		}
+		stable = append(stable, parsedVersion{version: r.version, semver: ver})
+	}
+
+	sortVersions := func(versions []parsedVersion) []string {
+		sort.SliceStable(versions, func(i, j int) bool {
+			return compareSemverWithBuild(versions[i].semver, versions[j].semver) > 0
+		})
+		var sorted []string
+		for _, v := range versions {
+			sorted = append(sorted, v.version)
+		}
+		return sorted
+	}
+
+	v := &Versions{
+		stable:   sortVersions(stable),
+		unstable: sortVersions(unstable),
+		invalid:  invalid,
+	}
+	v.log(logger)
+
+	return v
+}
+
+// compareSemverWithBuild compares semver versions and breaks the ties with
+// the build metadata, so post releases are ordered, e.g 1.0+post.2 > 1.0.
+func compareSemverWithBuild(a, b semver.Version) int {
+	if c := a.Compare(b); c != 0 {
+		return c
+	}
+	for i := 0; i < len(a.Build) && i < len(b.Build); i++ {
+		ai, aErr := strconv.ParseUint(a.Build[i], 10, 64)
+		bi, bErr := strconv.ParseUint(b.Build[i], 10, 64)
+		if aErr == nil && bErr == nil {
+			if ai != bi {
+				return sign(int(ai) - int(bi))
+			}
+			continue
+		}
+		if c := strings.Compare(a.Build[i], b.Build[i]); c != 0 {
+			return c
+		}
+	}
+	return sign(len(a.Build) - len(b.Build))
+}
+
+func (v *Versions) log(logger zerolog.Logger) {
+	logger.Info().Int("len", len(v.stable)).
+		Str("version", strings.Join(v.stable, ",")).
+		Msg("stable versions")
+
+	logger.Info().Int("len", len(v.unstable)).
+		Str("version", strings.Join(v.unstable, ",")).
+		Msg("unstable versions")
+
+	if len(v.invalid) > 0 {
+		logger.Warn().Int("len", len(v.invalid)).
+			Str("version", strings.Join(v.invalid, ",")).
+			Msg("invalid versions ignored")
+	}
+}
+
+// selectLatestVersion returns the first stable version satisfying the
+// semver range.
+func selectLatestVersion(v *Versions, semverRange string, parse versionParser) (string, error) {
+	if len(v.stable) == 0 {
+		return "", fmt.Errorf("no stable versions found")
+	}
+
+	if semverRange == "" {
+		return v.stable[0], nil
+	}
+
+	expectedRange, err := semver.ParseRange(semverRange)
+	if err != nil {
+		return "", err
+	}
+	for _, ver := range v.stable {
+		v, err := parse(ver)
+		if err != nil {
+			return "", err
+		}
+		if expectedRange(v) {
+			return ver, nil
+		}
+	}
+
+	return "", fmt.Errorf("no version found matching semver, semver='%s'", semverRange)
+}
+
+// checkSemverScheme returns an error if the fact uses a version scheme
+// other than semver, the registries only support semver ranges.
+func checkSemverScheme(provider ProviderType, fact *core.Fact) error {
+	switch fact.VersionScheme {
+	case "", core.SemverVersionScheme:
+		return nil
+	}
+	return fmt.Errorf("version scheme not supported by provider %s: %s", provider, fact.VersionScheme)
+}
+
+// getRegistryJSON decodes the JSON document of a registry API.
+func getRegistryJSON(u string, header http.Header, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	for k, values := range header {
+		for _, value := range values {
+			req.Header.Add(k, value)
+		}
+	}
+	req.Header.Set("User-Agent", registryUserAgent)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("error getting registry metadata: %d, url=%s", resp.StatusCode, u)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rs/zerolog"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var _ = Describe("Registry Providers", func() {
+	Describe("Split releases", func() {
+		It("sorts the versions and drops the withdrawn releases", func() {
+			releases := []registryRelease{
+				{version: "1.2.0"},
+				{version: "1.10.0"},
+				{version: "2.0.0", withdrawn: true},
+				{version: "2.0.0-rc.1"},
+				{version: "1.11.0", prerelease: true},
+				{version: "latest"},
+			}
+			v := splitReleases(zerolog.Nop(), releases, parseSanitizedSemver)
+			Expect(v.stable).To(Equal([]string{"1.10.0", "1.2.0"}))
+			Expect(v.unstable).To(Equal([]string{"2.0.0-rc.1", "1.11.0"}))
+			Expect(v.invalid).To(Equal([]string{"latest"}))
+		})
+		It("selects the highest stable version of the range", func() {
+			v := &Versions{stable: []string{"v1.10.0", "v1.2.0"}}
+			version, err := selectLatestVersion(v, "<1.10.0", parseSanitizedSemver)
+			Expect(err).To(BeNil())
+			Expect(version).To(Equal("v1.2.0"))
+
+			_, err = selectLatestVersion(&Versions{unstable: []string{"2.0.0-rc.1"}}, "", parseSanitizedSemver)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("PEP 440 versions", func() {
+		It("converts the versions to semver", func() {
+			tests := map[string]string{
+				"1":             "1.0.0",
+				"2021.01":       "2021.1.0",
+				"1.2.3":         "1.2.3",
+				"1.0rc1":        "1.0.0-rc.1.final",
+				"1.0.0-alpha.2": "1.0.0-a.2.final",
+				"1.0b":          "1.0.0-b.0.final",
+				"1.0.dev3":      "1.0.0-0.dev.3",
+				"1.0.post1":     "1.0.0+post.1",
+				"1.0-2":         "1.0.0+post.2",
+				"1.0a1.dev1":    "1.0.0-a.1.dev.1",
+			}
+			for pep440, expected := range tests {
+				v, err := parsePEP440Version(pep440)
+				Expect(err).To(BeNil(), pep440)
+				Expect(v.String()).To(Equal(expected), pep440)
+			}
+			for _, v := range []string{"1.2.3.4", "1!1.0", "foo"} {
+				_, err := parsePEP440Version(v)
+				Expect(err).To(HaveOccurred(), v)
+			}
+		})
+
+		It("orders the versions as PEP 440", func() {
+			// The example of the PEP 440 summary of permitted suffixes.
+			ordered := []string{"1.0.dev456", "1.0a1", "1.0a2.dev456", "1.0a12.dev456", "1.0a12",
+				"1.0b1.dev456", "1.0b2", "1.0b2.post345", "1.0c1.dev456", "1.0c1", "1.0rc2", "1.0",
+				"1.0.post456", "1.1.dev1"}
+			for i := 1; i < len(ordered); i++ {
+				a, err := parsePEP440Version(ordered[i-1])
+				Expect(err).To(BeNil())
+				b, err := parsePEP440Version(ordered[i])
+				Expect(err).To(BeNil())
+				Expect(compareSemverWithBuild(a, b)).To(Equal(-1), "%s < %s", ordered[i-1], ordered[i])
+			}
+		})
+
+		It("normalizes the project names", func() {
+			Expect(normalizePyPIProject("Zope.Interface")).To(Equal("zope-interface"))
+			Expect(normalizePyPIProject("typing__extensions")).To(Equal("typing-extensions"))
+		})
+	})
+
+	Describe("Go module retractions", func() {
+		It("parses the retract directives", func() {
+			mod := []byte(`// Deprecated: use example.com/mod/v2
+module example.com/mod
+
+retract v1.0.1 // broken build
+retract [v1.1.0, v1.1.5]
+retract (
+	v1.2.0
+)
+`)
+			Expect(isGoModDeprecated(mod)).To(BeTrue())
+			retractions := parseGoModRetractions(mod)
+			Expect(retractions).To(HaveLen(3))
+			for _, v := range []string{"v1.0.1", "v1.1.3", "v1.2.0"} {
+				Expect(retractions.contains(v)).To(BeTrue(), v)
+			}
+			for _, v := range []string{"v1.0.0", "v1.1.6"} {
+				Expect(retractions.contains(v)).To(BeFalse(), v)
+			}
+		})
+		It("escapes the module paths", func() {
+			Expect(escapeModulePath("github.com/BurntSushi/toml")).To(Equal("github.com/!burnt!sushi/toml"))
+		})
+	})
+
+	Describe("Get facts", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			documents := map[string]string{
+				"/pypi/black/json": `{"releases": {
+					"21.12b0": [{"yanked": false}],
+					"22.1.0": [{"yanked": false}],
+					"22.3.0": [{"yanked": true}],
+					"22.2.0": []
+				}}`,
+				"/npm/@scope%2Fcli": `{"versions": {
+					"1.0.0": {},
+					"1.1.0": {"deprecated": "use 1.1.1"},
+					"1.1.1": {"deprecated": ""},
+					"2.0.0-beta.1": {}
+				}}`,
+				"/crates/crates/ripgrep": `{"versions": [
+					{"num": "13.0.0", "yanked": false},
+					{"num": "14.0.0", "yanked": true},
+					{"num": "12.1.1", "yanked": false}
+				]}`,
+				"/goproxy/github.com/!burnt!sushi/toml/@v/list":       "v1.0.0\nv1.1.0\nv1.2.0\nv1.3.0-rc.1\n",
+				"/goproxy/github.com/!burnt!sushi/toml/@v/v1.2.0.mod": "module github.com/BurntSushi/toml\n\nretract v1.2.0\n",
+			}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				doc, ok := documents[r.URL.EscapedPath()]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write([]byte(doc))
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("returns the latest version of each registry", func() {
+			tests := []struct {
+				provider Provider
+				expected string
+			}{
+				{provider: NewPyPI(server.URL+"/pypi", "Black"), expected: "22.1.0"},
+				{provider: NewNpm(server.URL+"/npm/", "@scope/cli"), expected: "1.1.1"},
+				{provider: NewCrates(server.URL+"/crates", "ripgrep"), expected: "13.0.0"},
+				{provider: NewGoProxy(server.URL+"/goproxy", "github.com/BurntSushi/toml"), expected: "v1.1.0"},
+			}
+			for _, tt := range tests {
+				value, err := tt.provider.GetFact(&core.Fact{Kind: core.VersionFactKind})
+				Expect(err).To(BeNil())
+				Expect(value).To(Equal(tt.expected))
+			}
+		})
+		It("applies the semver range", func() {
+			c := NewCrates(server.URL+"/crates", "ripgrep")
+			value, err := c.GetFact(&core.Fact{Kind: core.VersionFactKind, Semver: "<13.0.0"})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("12.1.1"))
+		})
+		It("fails because of a version scheme other than semver", func() {
+			n := NewNpm(server.URL+"/npm", "@scope/cli")
+			_, err := n.GetFact(&core.Fact{Kind: core.VersionFactKind, VersionScheme: core.APKVersionScheme})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
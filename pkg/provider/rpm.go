@@ -0,0 +1,326 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+const (
+	rpmRepomdPath      = "repodata/repomd.xml"
+	rpmPrimaryDataType = "primary"
+	rpmNoArch          = "noarch"
+)
+
+type Rpm struct {
+	log zerolog.Logger
+	filtered
+
+	baseURL string
+	arch    string
+	pkgName string
+
+	// pkgs caches the packages of the primary metadata matching pkgName.
+	pkgs []*RpmPackageMeta
+}
+
+func NewRpm(baseURL, arch, pkgName string) *Rpm {
+	return &Rpm{
+		log:     log.With().Str("provider", string(ProviderRpm)).Logger(),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		arch:    arch,
+		pkgName: pkgName,
+	}
+}
+
+// rpmRepomd is the repodata/repomd.xml index of a repository.
+type rpmRepomd struct {
+	Data []struct {
+		Type     string `xml:"type,attr"`
+		Location struct {
+			Href string `xml:"href,attr"`
+		} `xml:"location"`
+	} `xml:"data"`
+}
+
+// rpmPrimaryPackage is a package element of the primary metadata.
+type rpmPrimaryPackage struct {
+	Name    string `xml:"name"`
+	Arch    string `xml:"arch"`
+	Version struct {
+		Epoch string `xml:"epoch,attr"`
+		Ver   string `xml:"ver,attr"`
+		Rel   string `xml:"rel,attr"`
+	} `xml:"version"`
+	Checksum struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"checksum"`
+	Location struct {
+		Href string `xml:"href,attr"`
+	} `xml:"location"`
+	Format struct {
+		SourceRpm string `xml:"sourcerpm"`
+	} `xml:"format"`
+}
+
+type RpmPackageMeta struct {
+	name    string
+	arch    string
+	epoch   int
+	version string
+	release string
+	// sourceRpm is the source package file, e.g nginx-1.14.1-9.el8.src.rpm.
+	sourceRpm string
+	location  string
+	checksum  string
+}
+
+// evr returns the [epoch:]version-release of the package.
+func (r *RpmPackageMeta) evr() string {
+	return rpmEVR{epoch: r.epoch, version: r.version, release: r.release}.String()
+}
+
+// sourceName returns the name of the source package.
+func (r *RpmPackageMeta) sourceName() string {
+	// nginx-1.14.1-9.el8.src.rpm: drop the version and the release.
+	name := strings.TrimSuffix(r.sourceRpm, ".src.rpm")
+	for i := 0; i < 2; i++ {
+		if j := strings.LastIndex(name, "-"); j > 0 {
+			name = name[:j]
+		}
+	}
+	if name == "" {
+		return r.name
+	}
+	return name
+}
+
+func (r *Rpm) get(href string) (*http.Response, error) {
+	u, err := url.Parse(fmt.Sprintf("%s/%s", r.baseURL, strings.TrimPrefix(href, "/")))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error getting rpm repository file: %d, url=%s", resp.StatusCode, u)
+	}
+
+	return resp, nil
+}
+
+// getPrimaryLocation returns the location of the primary metadata
+// referenced by repomd.xml.
+func (r *Rpm) getPrimaryLocation() (string, error) {
+	resp, err := r.get(rpmRepomdPath)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var repomd rpmRepomd
+	if err := xml.NewDecoder(resp.Body).Decode(&repomd); err != nil {
+		return "", err
+	}
+
+	for _, data := range repomd.Data {
+		if data.Type == rpmPrimaryDataType && data.Location.Href != "" {
+			return data.Location.Href, nil
+		}
+	}
+
+	return "", fmt.Errorf("primary metadata not found in %s", rpmRepomdPath)
+}
+
+func (r *Rpm) getPackages() ([]*RpmPackageMeta, error) {
+	if r.pkgs != nil {
+		return r.pkgs, nil
+	}
+
+	location, err := r.getPrimaryLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// The primary metadata can be compressed, e.g primary.xml.gz.
+	compression := path.Ext(location)
+	if compression == ".xml" {
+		compression = ""
+	}
+	data, err := decompressIndex(resp.Body, compression)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs, err := parseRpmPrimary(data, r.pkgName)
+	if err != nil {
+		return nil, err
+	}
+
+	r.pkgs = pkgs
+	return r.pkgs, nil
+}
+
+func (r *Rpm) getLatestPackage(scheme core.VersionScheme, semverRange string) (*RpmPackageMeta, error) {
+	pkgs, err := r.getPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*RpmPackageMeta
+	for _, pkg := range pkgs {
+		if pkg.arch == r.arch || pkg.arch == rpmNoArch {
+			candidates = append(candidates, pkg)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("Package not found: %s, arch=%s", r.pkgName, r.arch)
+	}
+
+	pkg, err := selectLatestRpmPackage(candidates, r.filter, scheme, semverRange)
+	if err != nil {
+		return nil, err
+	}
+
+	r.log.Info().Str("version", pkg.evr()).
+		Str("semver", semverRange).
+		Msg("version found")
+
+	return pkg, nil
+}
+
+func (r *Rpm) GetFact(fact *core.Fact) (string, error) {
+	pkg, err := r.getLatestPackage(fact.VersionScheme, fact.Semver)
+	if err != nil {
+		return "", err
+	}
+
+	switch fact.Kind {
+	case core.VersionFactKind, core.StringFactKind:
+		return pkg.evr(), nil
+	case core.OriginFactKind:
+		return pkg.sourceName(), nil
+	case core.ChecksumFactKind:
+		return pkg.checksum, nil
+	}
+
+	return "", fmt.Errorf("fact kind not supported by provider %s: %s", ProviderRpm, fact.Kind)
+}
+
+// selectLatestRpmPackage returns the package with the highest EVR
+// selected by the filter and satisfying the range, using the rpm version ordering.
+func selectLatestRpmPackage(pkgs []*RpmPackageMeta, filter *VersionFilter, scheme core.VersionScheme, semverRange string) (*RpmPackageMeta, error) {
+	sorted := make([]*RpmPackageMeta, len(pkgs))
+	copy(sorted, pkgs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return CompareRPMVersions(sorted[i].evr(), sorted[j].evr()) > 0
+	})
+
+	expectedRange, err := parseVersionRange(scheme, semverRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, pkg := range sorted {
+		// The filter selects the EVR as it is the version of the fact.
+		if !filter.Match(pkg.evr()) {
+			continue
+		}
+		// semver ranges only make sense against the upstream version.
+		version := pkg.evr()
+		if scheme == "" || scheme == core.SemverVersionScheme {
+			version = pkg.version
+		}
+		versions = append(versions, version)
+
+		ok, err := expectedRange(filter.Transform(version))
+		if err != nil {
+			log.Warn().Str("version", version).
+				Err(err).
+				Msg("parsing version failed")
+			continue
+		}
+		if ok {
+			return pkg, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no version found matching semver, versions=%s, semver='%s'", strings.Join(versions, ","), semverRange)
+}
+
+// parseRpmPrimary parses the package elements of the primary metadata
+// and returns the ones named pkgName.
+func parseRpmPrimary(data []byte, pkgName string) ([]*RpmPackageMeta, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var pkgs []*RpmPackageMeta
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "package" {
+			continue
+		}
+
+		var p rpmPrimaryPackage
+		if err := decoder.DecodeElement(&p, &start); err != nil {
+			return nil, err
+		}
+		if p.Name != pkgName {
+			continue
+		}
+
+		if p.Version.Ver == "" {
+			return nil, fmt.Errorf("rpm package meta field is empty: version, package=%s", p.Name)
+		}
+		epoch := 0
+		if p.Version.Epoch != "" {
+			if epoch, err = strconv.Atoi(p.Version.Epoch); err != nil {
+				return nil, fmt.Errorf("Invalid epoch of package %s: %s", p.Name, p.Version.Epoch)
+			}
+		}
+
+		pkgs = append(pkgs, &RpmPackageMeta{
+			name:      p.Name,
+			arch:      p.Arch,
+			epoch:     epoch,
+			version:   p.Version.Ver,
+			release:   p.Version.Rel,
+			sourceRpm: p.Format.SourceRpm,
+			location:  p.Location.Href,
+			checksum:  p.Checksum.Value,
+		})
+	}
+
+	return pkgs, nil
+}
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+var _ = Describe("Rpm Provider", func() {
+	var repomd, primary []byte
+
+	BeforeEach(func() {
+		var err error
+		repomd, err = ioutil.ReadFile("testdata/rpm/repodata/repomd.xml")
+		Expect(err).To(BeNil())
+		primary, err = ioutil.ReadFile("testdata/rpm/primary.xml")
+		Expect(err).To(BeNil())
+	})
+
+	Describe("Parse primary metadata", func() {
+		It("parses the packages of a name", func() {
+			pkgs, err := parseRpmPrimary(primary, "nginx-filesystem")
+			Expect(err).To(BeNil())
+			Expect(pkgs).To(HaveLen(1))
+			Expect(pkgs[0]).To(Equal(&RpmPackageMeta{
+				name:      "nginx-filesystem",
+				arch:      "noarch",
+				epoch:     1,
+				version:   "1.20.1",
+				release:   "1.module+el8.6.0+1234+a1b2c3d4",
+				sourceRpm: "nginx-1.20.1-1.module+el8.6.0+1234+a1b2c3d4.src.rpm",
+				location:  "Packages/nginx-filesystem-1.20.1-1.module+el8.6.0+1234+a1b2c3d4.noarch.rpm",
+				checksum:  "0e1d2c3b4a5968778695a4b3c2d1e0f90e1d2c3b4a5968778695a4b3c2d1e0f9",
+			}))
+			Expect(pkgs[0].evr()).To(Equal("1:1.20.1-1.module+el8.6.0+1234+a1b2c3d4"))
+			Expect(pkgs[0].sourceName()).To(Equal("nginx"))
+		})
+		It("fails because of an invalid epoch", func() {
+			_, err := parseRpmPrimary([]byte(`<metadata><package><name>nginx</name><version epoch="x" ver="1.0" rel="1"/></package></metadata>`), "nginx")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("RPM versions", func() {
+		It("compares versions like rpmvercmp", func() {
+			tests := []struct {
+				a, b     string
+				expected int
+			}{
+				{a: "1.0", b: "1.0", expected: 0},
+				{a: "1.0", b: "2.0", expected: -1},
+				{a: "2.0.1", b: "2.0", expected: 1},
+				{a: "2.0.1a", b: "2.0.1", expected: 1},
+				{a: "5.5p1", b: "5.5p2", expected: -1},
+				{a: "5.5p10", b: "5.5p1", expected: 1},
+				{a: "10xyz", b: "10.1xyz", expected: -1},
+				{a: "xyz.4", b: "8", expected: -1},
+				{a: "1.0a", b: "1.0aa", expected: -1},
+				{a: "2a", b: "2.0", expected: -1},
+				{a: "1.0", b: "1.fc4", expected: 1},
+				{a: "3.0.0_fc", b: "3.0.0.fc", expected: 0},
+				{a: "1++", b: "1_", expected: 0},
+				{a: "1.0~rc1", b: "1.0", expected: -1},
+				{a: "1.0~rc1", b: "1.0~rc2", expected: -1},
+				{a: "1.0~rc1~git123", b: "1.0~rc1", expected: -1},
+				{a: "1.0^", b: "1.0", expected: 1},
+				{a: "1.0^git1", b: "1.0.1", expected: -1},
+				{a: "1.0^git1~pre", b: "1.0^git1", expected: -1},
+				{a: "0001.0", b: "1.0", expected: 0},
+				{a: "1:1.0-1", b: "2.0-1", expected: 1},
+				{a: "1.1.1k-12.el8_9", b: "1.1.1k-6.el8_5", expected: 1},
+				{a: "1.0-1", b: "1.0", expected: 1},
+			}
+			for _, tt := range tests {
+				Expect(CompareRPMVersions(tt.a, tt.b)).To(Equal(tt.expected), "%s %s", tt.a, tt.b)
+				Expect(CompareRPMVersions(tt.b, tt.a)).To(Equal(-tt.expected), "%s %s", tt.b, tt.a)
+			}
+		})
+		It("validates versions", func() {
+			for _, v := range []string{"1.0", "1:1.1.1k-6.el8_5", "1.0~rc1-1"} {
+				Expect(ValidateRPMVersion(v)).To(BeTrue(), v)
+			}
+			for _, v := range []string{"", "x:1.0", "-1:1.0", "1.0 1", "1:-1"} {
+				Expect(ValidateRPMVersion(v)).To(BeFalse(), v)
+			}
+		})
+	})
+
+	Describe("Get facts", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repodata/repomd.xml":
+					_, _ = w.Write(repomd)
+				case "/repodata/7e2d1c0b-primary.xml.gz":
+					var buf bytes.Buffer
+					gzWriter := gzip.NewWriter(&buf)
+					_, _ = gzWriter.Write(primary)
+					_ = gzWriter.Close()
+					_, _ = w.Write(buf.Bytes())
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("returns the newest EVR of the arch", func() {
+			r := NewRpm(server.URL+"/", "x86_64", "nginx")
+			value, err := r.GetFact(&core.Fact{Kind: core.VersionFactKind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("1:1.20.1-1.module+el8.6.0+1234+a1b2c3d4"))
+
+			value, err = r.GetFact(&core.Fact{Kind: core.OriginFactKind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("nginx"))
+		})
+		It("matches noarch packages", func() {
+			r := NewRpm(server.URL, "aarch64", "nginx-filesystem")
+			value, err := r.GetFact(&core.Fact{Kind: core.VersionFactKind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("1:1.20.1-1.module+el8.6.0+1234+a1b2c3d4"))
+		})
+		It("applies the semver range to the upstream version", func() {
+			r := NewRpm(server.URL, "x86_64", "nginx")
+			value, err := r.GetFact(&core.Fact{Kind: core.VersionFactKind, Semver: ">=1.14.0 <1.20.0"})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("1:1.14.1-9.module+el8.0.0+4108+af250afe"))
+		})
+		It("applies the rpm version range", func() {
+			r := NewRpm(server.URL, "x86_64", "openssl-libs")
+			value, err := r.GetFact(&core.Fact{Kind: core.ChecksumFactKind, Semver: "<1:1.1.1k-12", VersionScheme: core.RPMVersionScheme})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("6f5e4d3c2b1a09f8e7d6c5b4a39281706f5e4d3c2b1a09f8e7d6c5b4a3928170"))
+		})
+		It("falls back to the next EVR when the filter excludes the newest", func() {
+			f, err := NewVersionFilter(core.Source{Exclude: `^1:1\.20\.`})
+			Expect(err).To(BeNil())
+
+			r := NewRpm(server.URL, "x86_64", "nginx")
+			r.setFilter(f)
+			value, err := r.GetFact(&core.Fact{Kind: core.VersionFactKind})
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("1:1.14.1-9.module+el8.0.0+4108+af250afe"))
+		})
+		It("fails because the package is not available for the arch", func() {
+			r := NewRpm(server.URL, "ppc64le", "nginx")
+			_, err := r.GetFact(&core.Fact{Kind: core.VersionFactKind})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"strconv"
+	"strings"
+)
+
+// RPM version comparison following rpm (rpmio/rpmvercmp.c).
+
+func isAlpha(c byte) bool { return isLower(c) || (c >= 'A' && c <= 'Z') }
+func isAlnum(c byte) bool { return isAlpha(c) || isDigit(c) }
+
+// rpmVerCmp compares two version or release strings like rpmvercmp.
+func rpmVerCmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	at := func(s string, i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return 0
+	}
+
+	one, two := 0, 0
+	for one < len(a) || two < len(b) {
+		for one < len(a) && !isAlnum(a[one]) && a[one] != '~' && a[one] != '^' {
+			one++
+		}
+		for two < len(b) && !isAlnum(b[two]) && b[two] != '~' && b[two] != '^' {
+			two++
+		}
+
+		// handle the tilde separator, it sorts before everything else
+		if at(a, one) == '~' || at(b, two) == '~' {
+			if at(a, one) != '~' {
+				return 1
+			}
+			if at(b, two) != '~' {
+				return -1
+			}
+			one++
+			two++
+			continue
+		}
+
+		// handle the caret separator, like tilde except that if one of
+		// the strings ends, the other is considered as higher version.
+		if at(a, one) == '^' || at(b, two) == '^' {
+			if one >= len(a) {
+				return -1
+			}
+			if two >= len(b) {
+				return 1
+			}
+			if a[one] != '^' {
+				return 1
+			}
+			if b[two] != '^' {
+				return -1
+			}
+			one++
+			two++
+			continue
+		}
+
+		// if we ran to the end of either, we are finished with the loop
+		if one >= len(a) || two >= len(b) {
+			break
+		}
+
+		// grab first completely alpha or completely numeric segment
+		str1, str2 := one, two
+		isNum := isDigit(a[str1])
+		segment := isAlpha
+		if isNum {
+			segment = isDigit
+		}
+		for str1 < len(a) && segment(a[str1]) {
+			str1++
+		}
+		for str2 < len(b) && segment(b[str2]) {
+			str2++
+		}
+
+		// segments of different types: numeric segments are always
+		// newer than alpha segments.
+		if two == str2 {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		seg1, seg2 := a[one:str1], b[two:str2]
+		if isNum {
+			// whichever number has more digits wins
+			seg1 = strings.TrimLeft(seg1, "0")
+			seg2 = strings.TrimLeft(seg2, "0")
+			if len(seg1) > len(seg2) {
+				return 1
+			}
+			if len(seg2) > len(seg1) {
+				return -1
+			}
+		}
+
+		if rc := strings.Compare(seg1, seg2); rc != 0 {
+			return rc
+		}
+
+		one, two = str1, str2
+	}
+
+	// all segments compared identically but the separators were different
+	if one >= len(a) && two >= len(b) {
+		return 0
+	}
+
+	// whichever version still has characters left over wins
+	if one >= len(a) {
+		return -1
+	}
+	return 1
+}
+
+type rpmEVR struct {
+	epoch   int
+	version string
+	release string
+}
+
+// parseRPMEVR parses [epoch:]version[-release].
+func parseRPMEVR(evr string) (rpmEVR, bool) {
+	var r rpmEVR
+
+	if i := strings.Index(evr, ":"); i >= 0 {
+		epoch, err := strconv.Atoi(evr[:i])
+		if err != nil || epoch < 0 {
+			return r, false
+		}
+		r.epoch = epoch
+		evr = evr[i+1:]
+	}
+
+	r.version = evr
+	if i := strings.LastIndex(evr, "-"); i >= 0 {
+		r.version = evr[:i]
+		r.release = evr[i+1:]
+	}
+
+	if r.version == "" || strings.ContainsAny(evr, " \t:") {
+		return r, false
+	}
+
+	return r, true
+}
+
+func (r rpmEVR) String() string {
+	evr := r.version
+	if r.release != "" {
+		evr += "-" + r.release
+	}
+	if r.epoch != 0 {
+		evr = strconv.Itoa(r.epoch) + ":" + evr
+	}
+	return evr
+}
+
+// ValidateRPMVersion returns true if the version is a valid RPM EVR.
+func ValidateRPMVersion(v string) bool {
+	_, ok := parseRPMEVR(v)
+	return ok
+}
+
+// CompareRPMVersions compares two RPM EVR using the rpm ordering.
+// It returns -1 if a < b, 0 if a == b and 1 if a > b.
+func CompareRPMVersions(a, b string) int {
+	ra, _ := parseRPMEVR(a)
+	rb, _ := parseRPMEVR(b)
+
+	if ra.epoch != rb.epoch {
+		return sign(ra.epoch - rb.epoch)
+	}
+	if rc := rpmVerCmp(ra.version, rb.version); rc != 0 {
+		return rc
+	}
+	return rpmVerCmp(ra.release, rb.release)
+}
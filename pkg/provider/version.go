@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/spiarh/gojo/pkg/core"
+)
+
+// versionRange returns true if the version is in the range.
+type versionRange func(version string) (bool, error)
+
+// parseVersionRange returns the range of a version scheme, an empty range
+// matches all the versions.
+func parseVersionRange(scheme core.VersionScheme, r string) (versionRange, error) {
+	if r == "" {
+		return func(string) (bool, error) { return true, nil }, nil
+	}
+
+	switch scheme {
+	case "", core.SemverVersionScheme:
+		expectedRange, err := semver.ParseRange(r)
+		if err != nil {
+			return nil, err
+		}
+		return func(version string) (bool, error) {
+			v, err := semver.Parse(version)
+			if err != nil {
+				return false, err
+			}
+			return expectedRange(v), nil
+		}, nil
+	case core.APKVersionScheme:
+		return newOrderedVersionRange(r, alpineVersionOrdering)
+	case core.DebVersionScheme:
+		return newOrderedVersionRange(r, debVersionOrdering)
+	case core.RPMVersionScheme:
+		return newOrderedVersionRange(r, rpmVersionOrdering)
+	}
+
+	return nil, fmt.Errorf("unknown version scheme: %s", scheme)
+}
+
+func newOrderedVersionRange(r string, ordering versionOrdering) (versionRange, error) {
+	expectedRange, err := parseRange(r, ordering)
+	if err != nil {
+		return nil, err
+	}
+	return func(version string) (bool, error) {
+		if !ordering.validate(version) {
+			return false, fmt.Errorf("invalid %s version: %s", ordering.name, version)
+		}
+		return expectedRange(version), nil
+	}, nil
+}
+
+// AlpineRange returns true if an apk version is in the range.
+type AlpineRange func(version string) bool
+
+// ParseAlpineRange parses a range of apk versions. The syntax is the same
+// as the semver ranges, e.g ">=1.18.0 <1.19.0 || >=1.20.0-r2", and supports
+// the apk fuzzy operator, e.g "~1.18" matches all the 1.18 versions.
+func ParseAlpineRange(s string) (AlpineRange, error) {
+	r, err := parseRange(s, alpineVersionOrdering)
+	if err != nil {
+		return nil, err
+	}
+	return AlpineRange(r), nil
+}
+
+// versionOrdering describes how versions of a scheme are validated and
+// compared.
+type versionOrdering struct {
+	name       string
+	validate   func(version string) bool
+	compare    func(a, b string) int
+	fuzzyMatch func(version, prefix string) bool
+}
+
+var alpineVersionOrdering = versionOrdering{
+	name:       string(core.APKVersionScheme),
+	validate:   ValidateAlpineVersion,
+	compare:    CompareAlpineVersions,
+	fuzzyMatch: FuzzyMatchAlpineVersion,
+}
+
+var debVersionOrdering = versionOrdering{
+	name:     string(core.DebVersionScheme),
+	validate: ValidateDebVersion,
+	compare:  CompareDebVersions,
+}
+
+var rpmVersionOrdering = versionOrdering{
+	name:     string(core.RPMVersionScheme),
+	validate: ValidateRPMVersion,
+	compare:  CompareRPMVersions,
+}
+
+// parseRange parses a range with the semver range syntax, e.g
+// ">=1.0 <2.0 || =3.0", the versions are compared with the ordering.
+func parseRange(s string, ordering versionOrdering) (func(string) bool, error) {
+	var orRanges []func(string) bool
+
+	for _, or := range strings.Split(s, "||") {
+		var andRanges []func(string) bool
+		fields := strings.Fields(or)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid %s range: %s", ordering.name, s)
+		}
+
+		for _, f := range fields {
+			r, err := parseComparator(f, ordering)
+			if err != nil {
+				return nil, err
+			}
+			andRanges = append(andRanges, r)
+		}
+
+		orRanges = append(orRanges, func(v string) bool {
+			for _, r := range andRanges {
+				if !r(v) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+
+	return func(v string) bool {
+		for _, r := range orRanges {
+			if r(v) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func parseComparator(s string, ordering versionOrdering) (func(string) bool, error) {
+	// Longest operators first.
+	operators := []string{">=", "<=", "!=", "==", ">", "<", "=", "~"}
+
+	op := ""
+	for _, o := range operators {
+		if strings.HasPrefix(s, o) {
+			op = o
+			break
+		}
+	}
+	version := strings.TrimPrefix(s, op)
+	if version == "" || !ordering.validate(version) {
+		return nil, fmt.Errorf("invalid %s version in range: %s", ordering.name, s)
+	}
+
+	compare := ordering.compare
+	switch op {
+	case ">=":
+		return func(v string) bool { return compare(v, version) >= 0 }, nil
+	case "<=":
+		return func(v string) bool { return compare(v, version) <= 0 }, nil
+	case "!=":
+		return func(v string) bool { return compare(v, version) != 0 }, nil
+	case ">":
+		return func(v string) bool { return compare(v, version) > 0 }, nil
+	case "<":
+		return func(v string) bool { return compare(v, version) < 0 }, nil
+	case "~":
+		if ordering.fuzzyMatch == nil {
+			return nil, fmt.Errorf("fuzzy operator not supported by %s versions: %s", ordering.name, s)
+		}
+		return func(v string) bool { return ordering.fuzzyMatch(v, version) }, nil
+	}
+	// "", "=" and "=="
+	return func(v string) bool { return compare(v, version) == 0 }, nil
+}
@@ -0,0 +1,94 @@
+package pullrequest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"golang.org/x/oauth2"
+
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+const gitHubHost = "github.com"
+
+// GitHub opens pull requests with the REST API v3.
+type GitHub struct {
+	client *github.Client
+	owner  string
+	repo   string
+}
+
+var _ Client = &GitHub{}
+
+// newGitHub returns a client authenticated with GITHUB_TOKEN, the API of
+// a GitHub Enterprise host is https://<host>/api/v3/ by default.
+func newGitHub(remote *util.GitRemote, apiURL string) (*GitHub, error) {
+	i := strings.LastIndex(remote.Path, "/")
+	g := &GitHub{
+		owner: remote.Path[:i],
+		repo:  remote.Path[i+1:],
+	}
+
+	token := os.Getenv(util.GitHubTokenEnvVar)
+	if token == "" {
+		return nil, fmt.Errorf("github token env var not set: %s", util.GitHubTokenEnvVar)
+	}
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	httpClient := oauth2.NewClient(context.Background(), ts)
+
+	if apiURL == "" && remote.Host != gitHubHost {
+		apiURL = fmt.Sprintf("https://%s/api/v3/", remote.Host)
+	}
+	if apiURL == "" {
+		g.client = github.NewClient(httpClient)
+		return g, nil
+	}
+
+	var err error
+	if g.client, err = github.NewEnterpriseClient(apiURL, apiURL, httpClient); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *GitHub) Open(pr *PullRequest) (string, error) {
+	ctx := context.Background()
+
+	prs, _, err := g.client.PullRequests.List(ctx, g.owner, g.repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  g.owner + ":" + pr.Head,
+		Base:  pr.Base,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(prs) > 0 {
+		updated, _, err := g.client.PullRequests.Edit(ctx, g.owner, g.repo, prs[0].GetNumber(), &github.PullRequest{
+			Title: github.String(pr.Title),
+			Body:  github.String(pr.Body),
+		})
+		if err != nil {
+			return "", err
+		}
+		return updated.GetHTMLURL(), nil
+	}
+
+	created, resp, err := g.client.PullRequests.Create(ctx, g.owner, g.repo, &github.NewPullRequest{
+		Title: github.String(pr.Title),
+		Head:  github.String(pr.Head),
+		Base:  github.String(pr.Base),
+		Body:  github.String(pr.Body),
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+			return "", fmt.Errorf("pull request of branch %s can't be created: %w", pr.Head, err)
+		}
+		return "", err
+	}
+	return created.GetHTMLURL(), nil
+}
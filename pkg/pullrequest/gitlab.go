@@ -0,0 +1,121 @@
+package pullrequest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+const gitLabTokenHeader = "PRIVATE-TOKEN"
+
+// GitLab opens merge requests with the REST API v4.
+type GitLab struct {
+	apiURL  string
+	token   string
+	project string
+}
+
+var _ Client = &GitLab{}
+
+type gitLabMergeRequest struct {
+	IID    int    `json:"iid,omitempty"`
+	WebURL string `json:"web_url,omitempty"`
+
+	SourceBranch string `json:"source_branch,omitempty"`
+	TargetBranch string `json:"target_branch,omitempty"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+}
+
+// newGitLab returns a client authenticated with GITLAB_TOKEN, the API is
+// https://<host>/api/v4 by default.
+func newGitLab(remote *util.GitRemote, apiURL string) (*GitLab, error) {
+	token := os.Getenv(util.GitLabTokenEnvVar)
+	if token == "" {
+		return nil, fmt.Errorf("gitlab token env var not set: %s", util.GitLabTokenEnvVar)
+	}
+	if apiURL == "" {
+		apiURL = fmt.Sprintf("https://%s/api/v4", remote.Host)
+	}
+
+	return &GitLab{
+		apiURL:  strings.TrimSuffix(apiURL, "/"),
+		token:   token,
+		project: remote.Path,
+	}, nil
+}
+
+func (g *GitLab) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	// The project is identified by its URL encoded path.
+	u := fmt.Sprintf("%s/projects/%s/%s", g.apiURL, url.PathEscape(g.project), path)
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(gitLabTokenHeader, g.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("error calling gitlab api: %d, url=%s, message=%s", resp.StatusCode, u, strings.TrimSpace(string(msg)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (g *GitLab) Open(pr *PullRequest) (string, error) {
+	query := url.Values{}
+	query.Set("state", "opened")
+	query.Set("source_branch", pr.Head)
+	query.Set("target_branch", pr.Base)
+
+	var mrs []gitLabMergeRequest
+	if err := g.do(http.MethodGet, "merge_requests?"+query.Encode(), nil, &mrs); err != nil {
+		return "", err
+	}
+
+	var mr gitLabMergeRequest
+	if len(mrs) > 0 {
+		update := gitLabMergeRequest{Title: pr.Title, Description: pr.Body}
+		if err := g.do(http.MethodPut, fmt.Sprintf("merge_requests/%d", mrs[0].IID), update, &mr); err != nil {
+			return "", err
+		}
+		return mr.WebURL, nil
+	}
+
+	create := gitLabMergeRequest{
+		SourceBranch: pr.Head,
+		TargetBranch: pr.Base,
+		Title:        pr.Title,
+		Description:  pr.Body,
+	}
+	if err := g.do(http.MethodPost, "merge_requests", create, &mr); err != nil {
+		return "", err
+	}
+	return mr.WebURL, nil
+}
@@ -0,0 +1,87 @@
+package pullrequest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+type Provider string
+
+const (
+	ProviderGitHub Provider = "github"
+	ProviderGitLab Provider = "gitlab"
+)
+
+// BranchPrefix prefixes the branches of the pull requests.
+const BranchPrefix = "gojo/"
+
+// PullRequest is a pull request, or a merge request, of a branch.
+type PullRequest struct {
+	// Head is the branch with the changes.
+	Head string
+	// Base is the branch the changes are merged into.
+	Base  string
+	Title string
+	Body  string
+}
+
+// Client opens the pull requests of a repository.
+type Client interface {
+	// Open opens the pull request, the open pull request of the head
+	// branch is updated if it exists. The URL of the pull request is
+	// returned.
+	Open(pr *PullRequest) (string, error)
+}
+
+// New returns the client of the provider of the remote, the provider is
+// detected from the host by default. The API URL is derived from the
+// host if empty.
+func New(remote *util.GitRemote, provider Provider, apiURL string) (Client, error) {
+	if provider == "" {
+		provider = ProviderGitHub
+		if strings.Contains(remote.Host, "gitlab") {
+			provider = ProviderGitLab
+		}
+	}
+
+	switch provider {
+	case ProviderGitHub:
+		return newGitHub(remote, apiURL)
+	case ProviderGitLab:
+		return newGitLab(remote, apiURL)
+	}
+	return nil, fmt.Errorf("pull request provider not recognized: %s", provider)
+}
+
+// BranchName returns the name of the branch of the new tag of an image.
+func BranchName(image, tag string) string {
+	return BranchPrefix + image + "/" + tag
+}
+
+// NewBody returns the body of the pull request of a build file, listing
+// the old and new values of the facts. The old build is nil for a new
+// build file.
+func NewBody(old, new *core.Build) string {
+	var b strings.Builder
+
+	oldTag := ""
+	oldValues := map[string]string{}
+	if old != nil {
+		oldTag = old.Image.Tag
+		for _, f := range old.Spec.Facts {
+			oldValues[f.Name] = f.Value
+		}
+	}
+
+	fmt.Fprintf(&b, "Update of the image `%s` from `%s` to `%s`.\n\n", new.Image.Name, oldTag, new.Image.Tag)
+	b.WriteString("| Fact | Old | New |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	for _, f := range new.Spec.Facts {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", f.Name, oldValues[f.Name], f.Value)
+	}
+
+	return b.String()
+}
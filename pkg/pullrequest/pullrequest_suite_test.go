@@ -0,0 +1,13 @@
+package pullrequest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPullRequest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pull Request Test Suite")
+}
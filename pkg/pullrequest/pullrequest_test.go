@@ -0,0 +1,133 @@
+package pullrequest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/pullrequest"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+var _ = Describe("Pull Request", func() {
+	It("lists the old and new fact values", func() {
+		old := &core.Build{
+			Image: &core.Image{Name: "haproxy", Tag: "2.3.4"},
+			Spec:  &core.ImageSpec{Facts: []*core.Fact{{Name: "VERSION", Value: "2.3.4"}}},
+		}
+		new := &core.Build{
+			Image: &core.Image{Name: "haproxy", Tag: "2.3.5"},
+			Spec:  &core.ImageSpec{Facts: []*core.Fact{{Name: "VERSION", Value: "2.3.5"}, {Name: "ALPINE", Value: "3.13"}}},
+		}
+		Expect(pullrequest.NewBody(old, new)).To(Equal("Update of the image `haproxy` from `2.3.4` to `2.3.5`.\n\n" +
+			"| Fact | Old | New |\n" +
+			"| --- | --- | --- |\n" +
+			"| VERSION | 2.3.4 | 2.3.5 |\n" +
+			"| ALPINE |  | 3.13 |\n"))
+		Expect(pullrequest.BranchName("haproxy", "2.3.5")).To(Equal("gojo/haproxy/2.3.5"))
+	})
+
+	Describe("Open", func() {
+		var server *httptest.Server
+		var requests []string
+		var existing bool
+
+		BeforeEach(func() {
+			requests = nil
+			existing = false
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				var body map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+
+				switch r.Method + " " + r.URL.Path {
+				// GitHub
+				case "GET /api/v3/repos/owner/repo/pulls":
+					Expect(r.URL.Query().Get("head")).To(Equal("owner:gojo/haproxy/2.3.5"))
+					if existing {
+						_, _ = w.Write([]byte(`[{"number": 7}]`))
+						return
+					}
+					_, _ = w.Write([]byte(`[]`))
+				case "POST /api/v3/repos/owner/repo/pulls":
+					Expect(body["head"]).To(Equal("gojo/haproxy/2.3.5"))
+					Expect(body["base"]).To(Equal("main"))
+					_, _ = w.Write([]byte(`{"number": 8, "html_url": "https://github.example.com/owner/repo/pull/8"}`))
+				case "PATCH /api/v3/repos/owner/repo/pulls/7":
+					Expect(body["body"]).To(Equal("body"))
+					_, _ = w.Write([]byte(`{"number": 7, "html_url": "https://github.example.com/owner/repo/pull/7"}`))
+				// GitLab, the project path is URL encoded.
+				case "GET /api/v4/projects/group/project/merge_requests":
+					Expect(r.Header.Get("PRIVATE-TOKEN")).To(Equal("gitlab-token"))
+					Expect(r.URL.RawPath).To(Equal("/api/v4/projects/group%2Fproject/merge_requests"))
+					Expect(r.URL.Query().Get("source_branch")).To(Equal("gojo/haproxy/2.3.5"))
+					if existing {
+						_, _ = w.Write([]byte(`[{"iid": 3}]`))
+						return
+					}
+					_, _ = w.Write([]byte(`[]`))
+				case "POST /api/v4/projects/group/project/merge_requests":
+					Expect(body["target_branch"]).To(Equal("main"))
+					_, _ = w.Write([]byte(`{"iid": 4, "web_url": "https://gitlab.example.com/group/project/-/merge_requests/4"}`))
+				case "PUT /api/v4/projects/group/project/merge_requests/3":
+					Expect(body["description"]).To(Equal("body"))
+					_, _ = w.Write([]byte(`{"iid": 3, "web_url": "https://gitlab.example.com/group/project/-/merge_requests/3"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			os.Setenv("GITHUB_TOKEN", "github-token")
+			os.Setenv("GITLAB_TOKEN", "gitlab-token")
+		})
+		AfterEach(func() {
+			server.Close()
+			os.Unsetenv("GITHUB_TOKEN")
+			os.Unsetenv("GITLAB_TOKEN")
+		})
+
+		pr := &pullrequest.PullRequest{Head: "gojo/haproxy/2.3.5", Base: "main", Title: "title", Body: "body"}
+
+		It("opens or updates a GitHub pull request", func() {
+			remote := &util.GitRemote{Host: "github.example.com", Path: "owner/repo"}
+			client, err := pullrequest.New(remote, "", server.URL+"/api/v3/")
+			Expect(err).To(BeNil())
+
+			url, err := client.Open(pr)
+			Expect(err).To(BeNil())
+			Expect(url).To(Equal("https://github.example.com/owner/repo/pull/8"))
+
+			existing = true
+			url, err = client.Open(pr)
+			Expect(err).To(BeNil())
+			Expect(url).To(Equal("https://github.example.com/owner/repo/pull/7"))
+			Expect(requests).To(Equal([]string{
+				"GET /api/v3/repos/owner/repo/pulls", "POST /api/v3/repos/owner/repo/pulls",
+				"GET /api/v3/repos/owner/repo/pulls", "PATCH /api/v3/repos/owner/repo/pulls/7",
+			}))
+		})
+		It("opens or updates a GitLab merge request", func() {
+			remote := &util.GitRemote{Host: "gitlab.example.com", Path: "group/project"}
+			client, err := pullrequest.New(remote, "", server.URL+"/api/v4")
+			Expect(err).To(BeNil())
+
+			url, err := client.Open(pr)
+			Expect(err).To(BeNil())
+			Expect(url).To(Equal("https://gitlab.example.com/group/project/-/merge_requests/4"))
+
+			existing = true
+			url, err = client.Open(pr)
+			Expect(err).To(BeNil())
+			Expect(url).To(Equal("https://gitlab.example.com/group/project/-/merge_requests/3"))
+		})
+		It("fails without token", func() {
+			os.Unsetenv("GITLAB_TOKEN")
+			_, err := pullrequest.New(&util.GitRemote{Host: "gitlab.com", Path: "group/project"}, "", "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
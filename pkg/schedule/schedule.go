@@ -0,0 +1,212 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the next run after a time.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// Interval runs at a fixed interval.
+type Interval time.Duration
+
+// Next returns t plus the interval.
+func (i Interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// Cron runs at the times matching a cron expression, the fields are
+// minute, hour, day of month, month and day of week.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// anyDay is true if the day of month or the day of week is *, the
+	// day matches both fields, else it matches one of them as in cron.
+	anyDay bool
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// maxSearch bounds the search of the next run of a cron expression, e.g
+// "0 0 30 2 *" never runs.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Parse parses a duration (1h30m), "@every <duration>", a macro (@daily)
+// or a cron expression with 5 fields.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		return parseInterval(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+	}
+	if expr, ok := macros[spec]; ok {
+		spec = expr
+	}
+	if !strings.Contains(spec, " ") {
+		return parseInterval(spec)
+	}
+
+	cron, err := parseCron(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+	}
+	return cron, nil
+}
+
+func parseInterval(value string) (Schedule, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval: %w", err)
+	}
+	if d < time.Minute {
+		return nil, fmt.Errorf("interval %s is shorter than 1m", d)
+	}
+	return Interval(d), nil
+}
+
+func parseCron(spec string) (*Cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, found %d", len(fields))
+	}
+
+	var c Cron
+	var err error
+	if c.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if c.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if c.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	// 7 is sunday as 0.
+	if c.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("never runs")
+	}
+	return &c, nil
+}
+
+// parseField returns the bits of the values of a comma-separated list of
+// *, n, a-b, */s, n/s and a-b/s.
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step: %s", part)
+			}
+			part = part[:i]
+		}
+
+		start, end := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = parseValue(bounds[0], min, max); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(bounds[1], min, max); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range: %s", part)
+			}
+		default:
+			value, err := parseValue(part, min, max)
+			if err != nil {
+				return 0, err
+			}
+			start = value
+			// n/s runs from n to the max as in cron.
+			if step == 1 {
+				end = value
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, min, max int) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %s", value)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d]", v, min, max)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t matching the expression in the
+// location of t, the zero time is returned if none is found.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(maxSearch)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *Cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
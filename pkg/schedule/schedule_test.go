@@ -0,0 +1,58 @@
+package schedule_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/schedule"
+)
+
+var _ = Describe("schedule", func() {
+	// Monday.
+	now := time.Date(2021, time.March, 1, 10, 17, 30, 0, time.UTC)
+
+	It("should parse the intervals", func() {
+		for _, spec := range []string{"90m", "@every 90m"} {
+			s, err := schedule.Parse(spec)
+			Expect(err).To(BeNil())
+			Expect(s.Next(now)).To(Equal(now.Add(90 * time.Minute)))
+		}
+	})
+
+	It("should return the next run of the cron expressions", func() {
+		tests := []struct {
+			spec string
+			next time.Time
+		}{
+			{"* * * * *", time.Date(2021, time.March, 1, 10, 18, 0, 0, time.UTC)},
+			{"0 * * * *", time.Date(2021, time.March, 1, 11, 0, 0, 0, time.UTC)},
+			{"@hourly", time.Date(2021, time.March, 1, 11, 0, 0, 0, time.UTC)},
+			{"*/15 * * * *", time.Date(2021, time.March, 1, 10, 30, 0, 0, time.UTC)},
+			{"5/20 * * * *", time.Date(2021, time.March, 1, 10, 25, 0, 0, time.UTC)},
+			{"30 2 * * *", time.Date(2021, time.March, 2, 2, 30, 0, 0, time.UTC)},
+			{"0 9-17/4 * * *", time.Date(2021, time.March, 1, 13, 0, 0, 0, time.UTC)},
+			{"0 0 * * 0", time.Date(2021, time.March, 7, 0, 0, 0, 0, time.UTC)},
+			{"0 0 * * 7", time.Date(2021, time.March, 7, 0, 0, 0, 0, time.UTC)},
+			{"0 0 1 1 *", time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)},
+			{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+			// The day of month or the day of week matches.
+			{"0 0 15 * 3", time.Date(2021, time.March, 3, 0, 0, 0, 0, time.UTC)},
+			{"0 12 1,15 * *", time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)},
+		}
+		for _, test := range tests {
+			s, err := schedule.Parse(test.spec)
+			Expect(err).To(BeNil(), test.spec)
+			Expect(s.Next(now)).To(Equal(test.next), test.spec)
+		}
+	})
+
+	It("should fail on invalid schedules", func() {
+		for _, spec := range []string{"", "1h x", "10s", "* * * *", "60 * * * *", "* * 0 * *",
+			"5-1 * * * *", "*/0 * * * *", "0 0 30 2 *", "@every"} {
+			_, err := schedule.Parse(spec)
+			Expect(err).To(HaveOccurred(), spec)
+		}
+	})
+})
@@ -1,9 +1,76 @@
 package util
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultGitUser is the user of the remotes without user, e.g
+// git@github.com.
+const defaultGitUser = "git"
+
+// Env vars of the tokens of the GitHub and GitLab APIs.
+const (
+	GitHubTokenEnvVar = "GITHUB_TOKEN"
+	GitLabTokenEnvVar = "GITLAB_TOKEN"
 )
 
+// GitRemote is the repository of a git remote.
+type GitRemote struct {
+	Host string
+	// Path is the path of the repository, e.g owner/repo or
+	// group/subgroup/project.
+	Path string
+}
+
+// ParseGitRemoteURL parses a git remote URL, e.g https://github.com/owner/repo.git,
+// ssh://git@gitlab.com/group/project.git or git@github.com:owner/repo.git.
+func ParseGitRemoteURL(rawURL string) (*GitRemote, error) {
+	// scp-like syntax, e.g git@github.com:owner/repo.git
+	if !strings.Contains(rawURL, "://") {
+		i := strings.Index(rawURL, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid git remote url: %s", rawURL)
+		}
+		host := rawURL[:i]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		return newGitRemote(host, rawURL[i+1:])
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return newGitRemote(u.Hostname(), u.Path)
+}
+
+func newGitRemote(host, path string) (*GitRemote, error) {
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return nil, fmt.Errorf("invalid git remote, host=%s, path=%s", host, path)
+	}
+	return &GitRemote{Host: host, Path: path}, nil
+}
+
 func OpenGitRepo(path string) (*git.Repository, error) {
 	opt := &git.PlainOpenOptions{DetectDotGit: true}
 	return git.PlainOpenWithOptions(path, opt)
@@ -43,3 +110,428 @@ func GitIsFileUnmodifiedStaging(status git.Status, relPath string) bool {
 	fStatus := status.File(relPath)
 	return fStatus.Staging == git.Unmodified
 }
+
+// ValidateRelGlob returns an error if the glob pattern is invalid or may
+// match files outside of its directory.
+func ValidateRelGlob(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty pattern")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%s: %w", pattern, err)
+	}
+	if path.IsAbs(pattern) || path.Clean(pattern) == ".." || strings.HasPrefix(path.Clean(pattern), "../") {
+		return fmt.Errorf("file outside of the directory: %s", pattern)
+	}
+	return nil
+}
+
+// GitMatchModifiedFiles returns the modified files of a directory, relative
+// to the worktree root, matching one of the glob patterns. The patterns
+// are relative to the directory.
+func GitMatchModifiedFiles(status git.Status, dir string, patterns []string) ([]string, error) {
+	for _, pattern := range patterns {
+		if err := ValidateRelGlob(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var files []string
+	for file, fileStatus := range status {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+		if fileStatus.Worktree == git.Unmodified && fileStatus.Staging == git.Unmodified {
+			continue
+		}
+		relPath := strings.TrimPrefix(file, prefix)
+		for _, pattern := range patterns {
+			// The pattern is validated.
+			if ok, _ := path.Match(path.Clean(pattern), relPath); ok {
+				files = append(files, file)
+				break
+			}
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// GitAuthOptions are the credentials used to push to a remote.
+type GitAuthOptions struct {
+	// SSHKeyFile is the private key of the ssh remotes, the ssh agent is
+	// used otherwise.
+	SSHKeyFile string
+	// SSHKeyPassphraseEnv is the env var containing the key passphrase.
+	SSHKeyPassphraseEnv string
+	// KnownHostsFile verifies the host keys, $SSH_KNOWN_HOSTS or
+	// ~/.ssh/known_hosts by default.
+	KnownHostsFile        string
+	InsecureIgnoreHostKey bool
+
+	// Username and TokenEnv are the basic auth of the https remotes, the
+	// env var contains the token or password.
+	Username string
+	TokenEnv string
+}
+
+// NewGitAuth returns the auth method of the remote URL and its
+// description for the error messages, the auth method is nil for the
+// remotes without credentials.
+func NewGitAuth(remoteURL string, opt GitAuthOptions) (transport.AuthMethod, string, error) {
+	ep, err := transport.NewEndpoint(remoteURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch ep.Protocol {
+	case "ssh":
+		return newGitSSHAuth(ep, opt)
+	case "http", "https":
+		if opt.TokenEnv == "" {
+			return nil, "no auth", nil
+		}
+		method := fmt.Sprintf("https basic auth with token env var %s", opt.TokenEnv)
+		token, ok := os.LookupEnv(opt.TokenEnv)
+		if !ok {
+			return nil, method, fmt.Errorf("%s: env var not set", method)
+		}
+		username := opt.Username
+		if username == "" {
+			username = defaultGitUser
+		}
+		return &githttp.BasicAuth{Username: username, Password: token}, method, nil
+	}
+
+	return nil, "no auth", nil
+}
+
+func newGitSSHAuth(ep *transport.Endpoint, opt GitAuthOptions) (transport.AuthMethod, string, error) {
+	user := ep.User
+	if user == "" {
+		user = defaultGitUser
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case opt.InsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	case opt.KnownHostsFile != "":
+		var err error
+		if hostKeyCallback, err = gitssh.NewKnownHostsCallback(opt.KnownHostsFile); err != nil {
+			return nil, "", fmt.Errorf("known hosts file %s: %w", opt.KnownHostsFile, err)
+		}
+	}
+
+	if opt.SSHKeyFile == "" {
+		method := "ssh agent"
+		auth, err := gitssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, method, fmt.Errorf("%s: %w", method, err)
+		}
+		auth.HostKeyCallback = hostKeyCallback
+		return auth, method, nil
+	}
+
+	method := fmt.Sprintf("ssh key file %s", opt.SSHKeyFile)
+	var passphrase string
+	if opt.SSHKeyPassphraseEnv != "" {
+		var ok bool
+		if passphrase, ok = os.LookupEnv(opt.SSHKeyPassphraseEnv); !ok {
+			return nil, method, fmt.Errorf("%s: passphrase env var not set: %s", method, opt.SSHKeyPassphraseEnv)
+		}
+	}
+	auth, err := gitssh.NewPublicKeysFromFile(user, opt.SSHKeyFile, passphrase)
+	if err != nil {
+		return nil, method, fmt.Errorf("%s: %w", method, err)
+	}
+	auth.HostKeyCallback = hostKeyCallback
+	return auth, method, nil
+}
+
+// GitRebaseFiles moves the current branch onto a commit and stages the
+// files again, the files changed by the commit are updated in the
+// worktree. The staged files, or the files with local changes, must not be
+// changed since the base commit.
+func GitRebaseFiles(repo *git.Repository, wt *git.Worktree, base, onto plumbing.Hash, paths []string) error {
+	baseCommit, err := repo.CommitObject(base)
+	if err != nil {
+		return err
+	}
+	ontoCommit, err := repo.CommitObject(onto)
+	if err != nil {
+		return err
+	}
+	baseTree, err := baseCommit.Tree()
+	if err != nil {
+		return err
+	}
+	ontoTree, err := ontoCommit.Tree()
+	if err != nil {
+		return err
+	}
+	changes, err := object.DiffTree(baseTree, ontoTree)
+	if err != nil {
+		return err
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+	staged := map[string]bool{}
+	for _, p := range paths {
+		staged[p] = true
+	}
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if staged[name] {
+				return fmt.Errorf("conflict, file changed on %s: %s", onto, name)
+			}
+			if _, ok := status[name]; ok && name != "" {
+				return fmt.Errorf("local changes would be overwritten by %s: %s", onto, name)
+			}
+		}
+	}
+
+	// The index is reset to the commit, the worktree keeps the files.
+	if err := wt.Reset(&git.ResetOptions{Commit: onto, Mode: git.MixedReset}); err != nil {
+		return err
+	}
+
+	root := wt.Filesystem.Root()
+	for _, change := range changes {
+		if change.From.Name != "" && change.From.Name != change.To.Name {
+			if err := os.Remove(filepath.Join(root, change.From.Name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if change.To.Name == "" {
+			continue
+		}
+
+		file, err := ontoTree.File(change.To.Name)
+		if err != nil {
+			return err
+		}
+		contents, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		mode, err := file.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(root, change.To.Name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), mode); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range paths {
+		if _, err := os.Stat(filepath.Join(root, p)); os.IsNotExist(err) {
+			if _, err := wt.Remove(p); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := wt.Add(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GitResetIndexFiles resets the index entries of the paths to the tree of
+// HEAD, as git reset -- <paths> does. The worktree is not changed.
+func GitResetIndexFiles(repo *git.Repository, paths []string) error {
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		file, err := tree.File(p)
+		if err == object.ErrFileNotFound {
+			if _, err := idx.Remove(p); err != nil && err != index.ErrEntryNotFound {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		e, err := idx.Entry(p)
+		if err == index.ErrEntryNotFound {
+			e = idx.Add(p)
+		} else if err != nil {
+			return err
+		}
+		// The zero times mark the entry as stale so its worktree file is
+		// hashed again.
+		*e = index.Entry{
+			Name: p,
+			Hash: file.Hash,
+			Mode: file.Mode,
+			Size: uint32(file.Size),
+		}
+	}
+
+	return repo.Storer.SetIndex(idx)
+}
+
+// GitWriteTree writes the tree of a commit with the worktree contents of
+// the paths, the paths missing from the worktree are removed from the
+// tree. Neither the index nor the worktree are changed.
+func GitWriteTree(repo *git.Repository, root string, base plumbing.Hash, paths []string) (plumbing.Hash, error) {
+	commit, err := repo.CommitObject(base)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	// The entries of the files, the submodules included, by path.
+	entries := map[string]object.TreeEntry{}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if entry.Mode != filemode.Dir {
+			entries[name] = entry
+		}
+	}
+
+	for _, p := range paths {
+		entry, err := writeWorktreeBlob(repo, filepath.Join(root, filepath.FromSlash(p)))
+		if os.IsNotExist(err) {
+			delete(entries, p)
+			continue
+		}
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entry.Name = path.Base(p)
+		entries[p] = entry
+	}
+
+	return writeTree(repo, "", entries)
+}
+
+// writeWorktreeBlob stores the contents of a worktree file, the target of
+// the symlinks is stored.
+func writeWorktreeBlob(repo *git.Repository, filePath string) (object.TreeEntry, error) {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return object.TreeEntry{}, err
+	}
+
+	var data []byte
+	mode := filemode.Regular
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(filePath)
+		if err != nil {
+			return object.TreeEntry{}, err
+		}
+		data, mode = []byte(filepath.ToSlash(target)), filemode.Symlink
+	case info.Mode().IsRegular():
+		if data, err = ioutil.ReadFile(filePath); err != nil {
+			return object.TreeEntry{}, err
+		}
+		if info.Mode()&0111 != 0 {
+			mode = filemode.Executable
+		}
+	default:
+		return object.TreeEntry{}, fmt.Errorf("not a regular file: %s", filePath)
+	}
+
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return object.TreeEntry{}, err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return object.TreeEntry{}, err
+	}
+	if err := w.Close(); err != nil {
+		return object.TreeEntry{}, err
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return object.TreeEntry{}, err
+	}
+	return object.TreeEntry{Mode: mode, Hash: hash}, nil
+}
+
+// writeTree stores the tree of the directory dir with the entries below
+// it, the subtrees are stored first.
+func writeTree(repo *git.Repository, dir string, entries map[string]object.TreeEntry) (plumbing.Hash, error) {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	tree := &object.Tree{}
+	subdirs := map[string]bool{}
+	for p, entry := range entries {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(p, prefix)
+		if i := strings.Index(name, "/"); i >= 0 {
+			subdirs[name[:i]] = true
+			continue
+		}
+		entry.Name = name
+		tree.Entries = append(tree.Entries, entry)
+	}
+	for name := range subdirs {
+		hash, err := writeTree(repo, prefix+name, entries)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// git sorts the directories as if their name ended with a slash.
+	sortName := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return sortName(tree.Entries[i]) < sortName(tree.Entries[j])
+	})
+
+	return storeObject(repo, tree)
+}
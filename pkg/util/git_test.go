@@ -0,0 +1,360 @@
+package util_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+var _ = Describe("git auth", func() {
+	const tokenEnv = "GOJO_TEST_GIT_TOKEN"
+
+	AfterEach(func() {
+		os.Unsetenv(tokenEnv)
+	})
+
+	It("should use the basic auth of the https remotes", func() {
+		os.Setenv(tokenEnv, "secret")
+
+		auth, method, err := util.NewGitAuth("https://github.com/owner/repo.git", util.GitAuthOptions{TokenEnv: tokenEnv})
+		Expect(err).To(BeNil())
+		Expect(method).To(ContainSubstring(tokenEnv))
+		Expect(auth).To(Equal(&githttp.BasicAuth{Username: "git", Password: "secret"}))
+	})
+
+	It("should fail if the token env var is not set", func() {
+		_, _, err := util.NewGitAuth("https://github.com/owner/repo.git", util.GitAuthOptions{TokenEnv: tokenEnv})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("https basic auth with token env var " + tokenEnv))
+	})
+
+	It("should not use auth without credentials", func() {
+		auth, method, err := util.NewGitAuth("https://github.com/owner/repo.git", util.GitAuthOptions{})
+		Expect(err).To(BeNil())
+		Expect(auth).To(BeNil())
+		Expect(method).To(Equal("no auth"))
+	})
+
+	It("should use the ssh key file of the ssh remotes", func() {
+		dir, err := ioutil.TempDir("", "gojo")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(BeNil())
+		keyFile := filepath.Join(dir, "id_rsa")
+		data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		Expect(ioutil.WriteFile(keyFile, data, 0600)).To(Succeed())
+
+		opt := util.GitAuthOptions{SSHKeyFile: keyFile, InsecureIgnoreHostKey: true}
+		auth, method, err := util.NewGitAuth("git@github.com:owner/repo.git", opt)
+		Expect(err).To(BeNil())
+		Expect(method).To(Equal("ssh key file " + keyFile))
+		Expect(auth.(*gitssh.PublicKeys).User).To(Equal("git"))
+
+		opt.SSHKeyPassphraseEnv = tokenEnv
+		_, _, err = util.NewGitAuth("ssh://deploy@gitlab.com/group/project.git", opt)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("passphrase env var not set"))
+	})
+})
+
+var _ = Describe("git remote", func() {
+	It("should parse the git remote urls", func() {
+		tests := map[string]util.GitRemote{
+			"https://github.com/owner/repo.git":          {Host: "github.com", Path: "owner/repo"},
+			"git@github.com:owner/repo.git":              {Host: "github.com", Path: "owner/repo"},
+			"ssh://git@gitlab.com/group/sub/project.git": {Host: "gitlab.com", Path: "group/sub/project"},
+			"https://user@git.example.com/owner/repo":    {Host: "git.example.com", Path: "owner/repo"},
+		}
+		for rawURL, expected := range tests {
+			remote, err := util.ParseGitRemoteURL(rawURL)
+			Expect(err).To(BeNil(), rawURL)
+			Expect(*remote).To(Equal(expected), rawURL)
+		}
+
+		for _, rawURL := range []string{"https://github.com/repo", "/srv/git/repo"} {
+			_, err := util.ParseGitRemoteURL(rawURL)
+			Expect(err).To(HaveOccurred(), rawURL)
+		}
+	})
+})
+
+var _ = Describe("git rebase", func() {
+	var dir string
+	var repo *git.Repository
+	var wt *git.Worktree
+	author := &object.Signature{Name: "gojo", Email: "gojo@example.com", When: time.Now()}
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		Expect(err).To(BeNil())
+		return string(data)
+	}
+	commit := func(names ...string) plumbing.Hash {
+		for _, name := range names {
+			_, err := wt.Add(name)
+			Expect(err).To(BeNil())
+		}
+		hash, err := wt.Commit("msg", &git.CommitOptions{Author: author})
+		Expect(err).To(BeNil())
+		return hash
+	}
+
+	var base, onto plumbing.Hash
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "gojo")
+		Expect(err).To(BeNil())
+		repo, err = git.PlainInit(dir, false)
+		Expect(err).To(BeNil())
+		wt, err = repo.Worktree()
+		Expect(err).To(BeNil())
+
+		write("a/.build.yaml", "a1")
+		write("b/.build.yaml", "b1")
+		base = commit("a/.build.yaml", "b/.build.yaml")
+
+		// The remote commit changes b and adds c.
+		write("b/.build.yaml", "b2")
+		write("c/.build.yaml", "c2")
+		onto = commit("b/.build.yaml", "c/.build.yaml")
+
+		// The local commit changes a on top of the base commit.
+		Expect(wt.Reset(&git.ResetOptions{Commit: base, Mode: git.HardReset})).To(Succeed())
+		write("a/.build.yaml", "a2")
+		commit("a/.build.yaml")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should rebase the files on the commit", func() {
+		Expect(util.GitRebaseFiles(repo, wt, base, onto, []string{"a/.build.yaml"})).To(Succeed())
+		hash := commit()
+
+		Expect(read("a/.build.yaml")).To(Equal("a2"))
+		Expect(read("b/.build.yaml")).To(Equal("b2"))
+		Expect(read("c/.build.yaml")).To(Equal("c2"))
+
+		rebased, err := repo.CommitObject(hash)
+		Expect(err).To(BeNil())
+		Expect(rebased.ParentHashes).To(Equal([]plumbing.Hash{onto}))
+		file, err := rebased.File("a/.build.yaml")
+		Expect(err).To(BeNil())
+		Expect(file.Contents()).To(Equal("a2"))
+
+		status, err := wt.Status()
+		Expect(err).To(BeNil())
+		Expect(status.IsClean()).To(BeTrue())
+	})
+
+	It("should fail if the files changed on the commit", func() {
+		err := util.GitRebaseFiles(repo, wt, base, onto, []string{"b/.build.yaml"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("conflict"))
+	})
+
+	It("should fail if the local changes would be overwritten", func() {
+		write("b/.build.yaml", "local")
+		err := util.GitRebaseFiles(repo, wt, base, onto, []string{"a/.build.yaml"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("local changes"))
+	})
+})
+
+var _ = Describe("git write tree", func() {
+	var dir string
+	var repo *git.Repository
+	var wt *git.Worktree
+	author := &object.Signature{Name: "gojo", Email: "gojo@example.com", When: time.Now()}
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	var head plumbing.Hash
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "gojo")
+		Expect(err).To(BeNil())
+		repo, err = git.PlainInit(dir, false)
+		Expect(err).To(BeNil())
+		wt, err = repo.Worktree()
+		Expect(err).To(BeNil())
+
+		for _, name := range []string{"a/.build.yaml", "a/old.lock", "a.txt", "b/.build.yaml"} {
+			write(name, "1")
+			_, err := wt.Add(name)
+			Expect(err).To(BeNil())
+		}
+		head, err = wt.Commit("msg", &git.CommitOptions{Author: author})
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should commit the worktree files without changing the index", func() {
+		write("a/.build.yaml", "2")
+		write("a/new.lock", "2")
+		Expect(os.Remove(filepath.Join(dir, "a/old.lock"))).To(Succeed())
+		write("b/.build.yaml", "2")
+		paths := []string{"a/.build.yaml", "a/new.lock", "a/old.lock"}
+
+		status, err := wt.Status()
+		Expect(err).To(BeNil())
+
+		tree, err := util.GitWriteTree(repo, dir, head, paths)
+		Expect(err).To(BeNil())
+		var signer *util.GitSigner
+		hash, err := signer.CommitTree(repo, tree, []plumbing.Hash{head}, "update", author)
+		Expect(err).To(BeNil())
+
+		ref, err := repo.Head()
+		Expect(err).To(BeNil())
+		Expect(ref.Hash()).To(Equal(head))
+		newStatus, err := wt.Status()
+		Expect(err).To(BeNil())
+		Expect(newStatus).To(Equal(status))
+
+		commit, err := repo.CommitObject(hash)
+		Expect(err).To(BeNil())
+		Expect(commit.ParentHashes).To(Equal([]plumbing.Hash{head}))
+		Expect(commit.Message).To(Equal("update"))
+		for name, content := range map[string]string{"a/.build.yaml": "2", "a/new.lock": "2", "a.txt": "1", "b/.build.yaml": "1"} {
+			file, err := commit.File(name)
+			Expect(err).To(BeNil())
+			Expect(file.Contents()).To(Equal(content), name)
+		}
+		_, err = commit.File("a/old.lock")
+		Expect(err).To(Equal(object.ErrFileNotFound))
+
+		// The tree is the tree git commits from the same files.
+		for _, name := range paths[:2] {
+			_, err := wt.Add(name)
+			Expect(err).To(BeNil())
+		}
+		_, err = wt.Remove("a/old.lock")
+		Expect(err).To(BeNil())
+		staged, err := wt.Commit("msg", &git.CommitOptions{Author: author})
+		Expect(err).To(BeNil())
+		stagedCommit, err := repo.CommitObject(staged)
+		Expect(err).To(BeNil())
+		Expect(stagedCommit.TreeHash).To(Equal(tree))
+	})
+})
+
+var _ = Describe("git reset index files", func() {
+	var dir string
+	var repo *git.Repository
+	var wt *git.Worktree
+	author := &object.Signature{Name: "gojo", Email: "gojo@example.com", When: time.Now()}
+
+	write := func(name, content string) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "gojo")
+		Expect(err).To(BeNil())
+		repo, err = git.PlainInit(dir, false)
+		Expect(err).To(BeNil())
+		wt, err = repo.Worktree()
+		Expect(err).To(BeNil())
+
+		write(".build.yaml", "1")
+		_, err = wt.Add(".build.yaml")
+		Expect(err).To(BeNil())
+		_, err = wt.Commit("msg", &git.CommitOptions{Author: author})
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should unstage the files", func() {
+		write(".build.yaml", "2")
+		write("new.lock", "2")
+		write("other.lock", "2")
+		for _, name := range []string{".build.yaml", "new.lock", "other.lock"} {
+			_, err := wt.Add(name)
+			Expect(err).To(BeNil())
+		}
+
+		Expect(util.GitResetIndexFiles(repo, []string{".build.yaml", "new.lock"})).To(Succeed())
+
+		status, err := wt.Status()
+		Expect(err).To(BeNil())
+		Expect(status.File(".build.yaml").Staging).To(Equal(git.Unmodified))
+		Expect(status.File(".build.yaml").Worktree).To(Equal(git.Modified))
+		Expect(status.File("new.lock").Staging).To(Equal(git.Untracked))
+		Expect(status.File("other.lock").Staging).To(Equal(git.Added))
+
+		// The restored file is clean once its content is restored.
+		write(".build.yaml", "1")
+		status, err = wt.Status()
+		Expect(err).To(BeNil())
+		Expect(status).NotTo(HaveKey(".build.yaml"))
+	})
+})
+
+var _ = Describe("git modified files", func() {
+	It("should validate the glob patterns", func() {
+		Expect(util.ValidateRelGlob("Containerfile")).To(Succeed())
+		Expect(util.ValidateRelGlob("docs/*.md")).To(Succeed())
+		Expect(util.ValidateRelGlob("./*.lock")).To(Succeed())
+
+		for _, pattern := range []string{"", "/etc/passwd", "../other/.build.yaml", "a/../../b", "[", ".."} {
+			Expect(util.ValidateRelGlob(pattern)).NotTo(Succeed(), pattern)
+		}
+	})
+
+	It("should match the modified files of the directory", func() {
+		status := git.Status{
+			"image/.build.yaml":    &git.FileStatus{Worktree: git.Modified, Staging: git.Unmodified},
+			"image/Containerfile":  &git.FileStatus{Worktree: git.Unmodified, Staging: git.Modified},
+			"image/deps.lock":      &git.FileStatus{Worktree: git.Untracked, Staging: git.Untracked},
+			"image/old.lock":       &git.FileStatus{Worktree: git.Deleted, Staging: git.Unmodified},
+			"image/README.md":      &git.FileStatus{Worktree: git.Modified, Staging: git.Unmodified},
+			"image/docs/x.lock":    &git.FileStatus{Worktree: git.Modified, Staging: git.Unmodified},
+			"image2/.build.yaml":   &git.FileStatus{Worktree: git.Modified, Staging: git.Unmodified},
+			"image/unmodified.txt": &git.FileStatus{Worktree: git.Unmodified, Staging: git.Unmodified},
+		}
+
+		files, err := util.GitMatchModifiedFiles(status, "image", []string{".build.yaml", "Containerfile", "./*.lock", "*.txt"})
+		Expect(err).To(BeNil())
+		Expect(files).To(Equal([]string{"image/.build.yaml", "image/Containerfile", "image/deps.lock", "image/old.lock"}))
+
+		_, err = util.GitMatchModifiedFiles(status, "image", []string{"../image2/.build.yaml"})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,347 @@
+package util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// GitSignFormatOpenPGP signs with an armored OpenPGP private key.
+	GitSignFormatOpenPGP = "openpgp"
+	// GitSignFormatSSH signs with a ssh private key in the SSHSIG format,
+	// as git does with gpg.format=ssh.
+	GitSignFormatSSH = "ssh"
+
+	sshSigMagic     = "SSHSIG"
+	sshSigVersion   = 1
+	sshSigNamespace = "git"
+	sshSigHashAlgo  = "sha512"
+	sshSigArmorHead = "-----BEGIN SSH SIGNATURE-----"
+	sshSigArmorTail = "-----END SSH SIGNATURE-----"
+)
+
+// GitSignOptions are the key signing the commits and tags.
+type GitSignOptions struct {
+	// Format is openpgp or ssh.
+	Format  string
+	KeyFile string
+	// PassphraseEnv is the env var containing the key passphrase.
+	PassphraseEnv string
+}
+
+// GitSigner signs the commits and tags, a nil signer creates unsigned
+// objects.
+type GitSigner struct {
+	openPGP *openpgp.Entity
+	ssh     ssh.Signer
+}
+
+// NewGitSigner reads the signing key, nil is returned without key file.
+func NewGitSigner(opt GitSignOptions) (*GitSigner, error) {
+	if opt.KeyFile == "" {
+		return nil, nil
+	}
+
+	var passphrase []byte
+	if opt.PassphraseEnv != "" {
+		value, ok := os.LookupEnv(opt.PassphraseEnv)
+		if !ok {
+			return nil, fmt.Errorf("signing key passphrase env var not set: %s", opt.PassphraseEnv)
+		}
+		passphrase = []byte(value)
+	}
+
+	data, err := ioutil.ReadFile(opt.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opt.Format {
+	case GitSignFormatOpenPGP, "":
+		entity, err := readOpenPGPKey(data, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("openpgp signing key %s: %w", opt.KeyFile, err)
+		}
+		return &GitSigner{openPGP: entity}, nil
+	case GitSignFormatSSH:
+		var signer ssh.Signer
+		if passphrase != nil {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(data, passphrase)
+		} else {
+			signer, err = ssh.ParsePrivateKey(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ssh signing key %s: %w", opt.KeyFile, err)
+		}
+		return &GitSigner{ssh: signer}, nil
+	}
+
+	return nil, fmt.Errorf("signing format not recognized: %s", opt.Format)
+}
+
+func readOpenPGPKey(data, passphrase []byte) (*openpgp.Entity, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("no private key found")
+	}
+
+	// go-git requires a decrypted key.
+	entity := entities[0]
+	if entity.PrivateKey.Encrypted {
+		if passphrase == nil {
+			return nil, fmt.Errorf("private key is encrypted and no passphrase is set")
+		}
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, err
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			if err := subkey.PrivateKey.Decrypt(passphrase); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return entity, nil
+}
+
+// Commit commits the staged changes, the commit of the ssh format is
+// signed once created and the current branch is moved to the signed
+// commit.
+func (s *GitSigner) Commit(repo *git.Repository, wt *git.Worktree, msg string, opts *git.CommitOptions) (plumbing.Hash, error) {
+	if s != nil && s.openPGP != nil {
+		opts.SignKey = s.openPGP
+	}
+
+	hash, err := wt.Commit(msg, opts)
+	if err != nil || s == nil || s.ssh == nil {
+		return hash, err
+	}
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return hash, err
+	}
+	unsigned := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(unsigned); err != nil {
+		return hash, err
+	}
+	if commit.PGPSignature, err = s.signObject(unsigned); err != nil {
+		return hash, err
+	}
+
+	signed, err := storeObject(repo, commit)
+	if err != nil {
+		return hash, err
+	}
+
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return hash, err
+	}
+	name := plumbing.HEAD
+	if head.Type() == plumbing.SymbolicReference {
+		name = head.Target()
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(name, signed)); err != nil {
+		return hash, err
+	}
+
+	return signed, nil
+}
+
+// CommitTree creates a commit of the tree without changing HEAD, the index
+// or the worktree.
+func (s *GitSigner) CommitTree(repo *git.Repository, tree plumbing.Hash, parents []plumbing.Hash, msg string, author *object.Signature) (plumbing.Hash, error) {
+	commit := &object.Commit{
+		Author:       *author,
+		Committer:    *author,
+		Message:      msg,
+		TreeHash:     tree,
+		ParentHashes: parents,
+	}
+
+	if s != nil {
+		unsigned := &plumbing.MemoryObject{}
+		if err := commit.EncodeWithoutSignature(unsigned); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		var err error
+		if s.ssh != nil {
+			commit.PGPSignature, err = s.signObject(unsigned)
+		} else {
+			commit.PGPSignature, err = s.openPGPSign(unsigned)
+		}
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	return storeObject(repo, commit)
+}
+
+// CreateTag creates an annotated tag of the commit, the tag message
+// ends with a new line as the signature follows the message.
+func (s *GitSigner) CreateTag(repo *git.Repository, name string, hash plumbing.Hash, opts *git.CreateTagOptions) (*plumbing.Reference, error) {
+	if !strings.HasSuffix(opts.Message, "\n") {
+		opts.Message += "\n"
+	}
+
+	if s == nil || s.ssh == nil {
+		if s != nil {
+			opts.SignKey = s.openPGP
+		}
+		return repo.CreateTag(name, hash, opts)
+	}
+
+	refName := plumbing.ReferenceName(path.Join("refs", "tags", name))
+	if _, err := repo.Storer.Reference(refName); err == nil {
+		return nil, git.ErrTagExists
+	} else if err != plumbing.ErrReferenceNotFound {
+		return nil, err
+	}
+
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     *opts.Tagger,
+		Message:    opts.Message,
+		TargetType: plumbing.CommitObject,
+		Target:     hash,
+	}
+	unsigned := &plumbing.MemoryObject{}
+	if err := tag.EncodeWithoutSignature(unsigned); err != nil {
+		return nil, err
+	}
+	sig, err := s.signObject(unsigned)
+	if err != nil {
+		return nil, err
+	}
+	tag.PGPSignature = sig
+
+	target, err := storeObject(repo, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := plumbing.NewHashReference(refName, target)
+	if err := repo.Storer.SetReference(ref); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+type encoder interface {
+	Encode(plumbing.EncodedObject) error
+}
+
+func storeObject(repo *git.Repository, o encoder) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
+
+func (s *GitSigner) signObject(obj *plumbing.MemoryObject) (string, error) {
+	r, err := obj.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return SSHSign(s.ssh, sshSigNamespace, data)
+}
+
+// openPGPSign returns the armored detached signature of the object, as
+// go-git signs the commits.
+func (s *GitSigner) openPGPSign(obj *plumbing.MemoryObject) (string, error) {
+	r, err := obj.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, s.openPGP, r, nil); err != nil {
+		return "", err
+	}
+	return sig.String(), nil
+}
+
+// SSHSign returns the armored SSHSIG signature of the message, see
+// PROTOCOL.sshsig of OpenSSH.
+func SSHSign(signer ssh.Signer, namespace string, message []byte) (string, error) {
+	hash := sha512.Sum512(message)
+	signedData := sshSigMagic + string(ssh.Marshal(struct {
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Hash      string
+	}{namespace, "", sshSigHashAlgo, string(hash[:])}))
+
+	var sig *ssh.Signature
+	var err error
+	// The RSA keys sign with SHA-512, ssh-rsa (SHA-1) is rejected by
+	// ssh-keygen.
+	if as, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		sig, err = as.SignWithAlgorithm(rand.Reader, []byte(signedData), ssh.SigAlgoRSASHA2512)
+	} else {
+		sig, err = signer.Sign(rand.Reader, []byte(signedData))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var blob bytes.Buffer
+	blob.WriteString(sshSigMagic)
+	if err := binary.Write(&blob, binary.BigEndian, uint32(sshSigVersion)); err != nil {
+		return "", err
+	}
+	blob.Write(ssh.Marshal(struct {
+		PublicKey string
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Signature string
+	}{
+		string(signer.PublicKey().Marshal()),
+		namespace,
+		"",
+		sshSigHashAlgo,
+		string(ssh.Marshal(sig)),
+	}))
+
+	encoded := base64.StdEncoding.EncodeToString(blob.Bytes())
+	var armored strings.Builder
+	armored.WriteString(sshSigArmorHead + "\n")
+	for len(encoded) > 70 {
+		armored.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	armored.WriteString(encoded + "\n")
+	armored.WriteString(sshSigArmorTail + "\n")
+
+	return armored.String(), nil
+}
@@ -0,0 +1,215 @@
+package util_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+var _ = Describe("git signing", func() {
+	var dir string
+	var repo *git.Repository
+	var wt *git.Worktree
+	author := &object.Signature{Name: "gojo", Email: "gojo@example.com", When: time.Now()}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "gojo")
+		Expect(err).To(BeNil())
+
+		repo, err = git.PlainInit(filepath.Join(dir, "repo"), false)
+		Expect(err).To(BeNil())
+		wt, err = repo.Worktree()
+		Expect(err).To(BeNil())
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "repo", ".build.yaml"), []byte("image: {}\n"), 0644)).To(Succeed())
+		_, err = wt.Add(".build.yaml")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should not sign without key file", func() {
+		signer, err := util.NewGitSigner(util.GitSignOptions{})
+		Expect(err).To(BeNil())
+		Expect(signer).To(BeNil())
+
+		hash, err := signer.Commit(repo, wt, "msg", &git.CommitOptions{Author: author})
+		Expect(err).To(BeNil())
+		commit, err := repo.CommitObject(hash)
+		Expect(err).To(BeNil())
+		Expect(commit.PGPSignature).To(BeEmpty())
+	})
+
+	It("should sign the commits and tags with an openpgp key", func() {
+		entity, err := openpgp.NewEntity("gojo", "", "gojo@example.com", nil)
+		Expect(err).To(BeNil())
+
+		var private bytes.Buffer
+		w, err := armor.Encode(&private, openpgp.PrivateKeyType, nil)
+		Expect(err).To(BeNil())
+		Expect(entity.SerializePrivate(w, nil)).To(Succeed())
+		Expect(w.Close()).To(Succeed())
+		keyFile := filepath.Join(dir, "key.asc")
+		Expect(ioutil.WriteFile(keyFile, private.Bytes(), 0600)).To(Succeed())
+
+		var public bytes.Buffer
+		w, err = armor.Encode(&public, openpgp.PublicKeyType, nil)
+		Expect(err).To(BeNil())
+		Expect(entity.Serialize(w)).To(Succeed())
+		Expect(w.Close()).To(Succeed())
+
+		signer, err := util.NewGitSigner(util.GitSignOptions{Format: util.GitSignFormatOpenPGP, KeyFile: keyFile})
+		Expect(err).To(BeNil())
+
+		hash, err := signer.Commit(repo, wt, "msg", &git.CommitOptions{Author: author})
+		Expect(err).To(BeNil())
+		commit, err := repo.CommitObject(hash)
+		Expect(err).To(BeNil())
+		_, err = commit.Verify(public.String())
+		Expect(err).To(BeNil())
+
+		branchHash, err := signer.CommitTree(repo, commit.TreeHash, []plumbing.Hash{hash}, "msg", author)
+		Expect(err).To(BeNil())
+		commit, err = repo.CommitObject(branchHash)
+		Expect(err).To(BeNil())
+		_, err = commit.Verify(public.String())
+		Expect(err).To(BeNil())
+
+		ref, err := signer.CreateTag(repo, "image/1.0.0", hash, &git.CreateTagOptions{Tagger: author, Message: "msg"})
+		Expect(err).To(BeNil())
+		tag, err := repo.TagObject(ref.Hash())
+		Expect(err).To(BeNil())
+		Expect(tag.Name).To(Equal("image/1.0.0"))
+		_, err = tag.Verify(public.String())
+		Expect(err).To(BeNil())
+	})
+
+	It("should sign with a ssh key", func() {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+		sshSigner, err := ssh.NewSignerFromKey(key)
+		Expect(err).To(BeNil())
+
+		sig, err := util.SSHSign(sshSigner, "git", []byte("message"))
+		Expect(err).To(BeNil())
+		verifySSHSig(sshSigner.PublicKey(), sig, []byte("message"))
+	})
+
+	It("should sign the commits of the current branch with a ssh key", func() {
+		keyFile := writeRSAKey(dir)
+		signer, err := util.NewGitSigner(util.GitSignOptions{Format: util.GitSignFormatSSH, KeyFile: keyFile})
+		Expect(err).To(BeNil())
+
+		hash, err := signer.Commit(repo, wt, "msg", &git.CommitOptions{Author: author})
+		Expect(err).To(BeNil())
+
+		head, err := repo.Head()
+		Expect(err).To(BeNil())
+		Expect(head.Name().IsBranch()).To(BeTrue())
+		Expect(head.Hash()).To(Equal(hash))
+
+		commit, err := repo.CommitObject(hash)
+		Expect(err).To(BeNil())
+		Expect(commit.PGPSignature).To(HavePrefix("-----BEGIN SSH SIGNATURE-----"))
+
+		branchHash, err := signer.CommitTree(repo, commit.TreeHash, []plumbing.Hash{hash}, "msg", author)
+		Expect(err).To(BeNil())
+		branchCommit, err := repo.CommitObject(branchHash)
+		Expect(err).To(BeNil())
+		unsigned := &plumbing.MemoryObject{}
+		Expect(branchCommit.EncodeWithoutSignature(unsigned)).To(Succeed())
+		r, err := unsigned.Reader()
+		Expect(err).To(BeNil())
+		message, err := ioutil.ReadAll(r)
+		Expect(err).To(BeNil())
+		data, err := ioutil.ReadFile(keyFile)
+		Expect(err).To(BeNil())
+		sshSigner, err := ssh.ParsePrivateKey(data)
+		Expect(err).To(BeNil())
+		verifySSHSig(sshSigner.PublicKey(), branchCommit.PGPSignature, message)
+		head, err = repo.Head()
+		Expect(err).To(BeNil())
+		Expect(head.Hash()).To(Equal(hash))
+
+		ref, err := signer.CreateTag(repo, "image/1.0.0", hash, &git.CreateTagOptions{Tagger: author, Message: "msg"})
+		Expect(err).To(BeNil())
+		tag, err := repo.TagObject(ref.Hash())
+		Expect(err).To(BeNil())
+		Expect(tag.Target).To(Equal(hash))
+		// go-git only decodes the PGP signatures of the tags.
+		Expect(tag.Message).To(HavePrefix("msg\n-----BEGIN SSH SIGNATURE-----"))
+
+		_, err = signer.CreateTag(repo, "image/1.0.0", hash, &git.CreateTagOptions{Tagger: author, Message: "msg"})
+		Expect(err).To(Equal(git.ErrTagExists))
+	})
+
+	It("should fail on unknown format", func() {
+		_, err := util.NewGitSigner(util.GitSignOptions{Format: "x509", KeyFile: writeRSAKey(dir)})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func writeRSAKey(dir string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).To(BeNil())
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	keyFile := filepath.Join(dir, "id_rsa")
+	Expect(ioutil.WriteFile(keyFile, data, 0600)).To(Succeed())
+	return keyFile
+}
+
+// verifySSHSig verifies an armored SSHSIG signature of the git namespace.
+func verifySSHSig(pub ssh.PublicKey, armored string, message []byte) {
+	lines := strings.Split(strings.TrimSpace(armored), "\n")
+	Expect(lines[0]).To(Equal("-----BEGIN SSH SIGNATURE-----"))
+	Expect(lines[len(lines)-1]).To(Equal("-----END SSH SIGNATURE-----"))
+	blob, err := base64.StdEncoding.DecodeString(strings.Join(lines[1:len(lines)-1], ""))
+	Expect(err).To(BeNil())
+	Expect(string(blob[:6])).To(Equal("SSHSIG"))
+
+	var sig struct {
+		Version   uint32
+		PublicKey []byte
+		Namespace string
+		Reserved  string
+		HashAlgo  string
+		Signature []byte
+	}
+	Expect(ssh.Unmarshal(blob[6:], &sig)).To(Succeed())
+	Expect(sig.Version).To(Equal(uint32(1)))
+	Expect(sig.PublicKey).To(Equal(pub.Marshal()))
+	Expect(sig.Namespace).To(Equal("git"))
+	Expect(sig.HashAlgo).To(Equal("sha512"))
+
+	var signature ssh.Signature
+	Expect(ssh.Unmarshal(sig.Signature, &signature)).To(Succeed())
+	hash := sha512.Sum512(message)
+	signed := "SSHSIG" + string(ssh.Marshal(struct {
+		Namespace, Reserved, HashAlgo, Hash string
+	}{"git", "", "sha512", string(hash[:])}))
+	Expect(pub.Verify([]byte(signed), &signature)).To(Succeed())
+}
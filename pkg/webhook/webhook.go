@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/pullrequest"
+	"github.com/spiarh/gojo/pkg/util"
+)
+
+const (
+	gitHubEventHeader     = "X-GitHub-Event"
+	gitHubSignatureHeader = "X-Hub-Signature-256"
+	gitHubSignaturePrefix = "sha256="
+	gitLabEventHeader     = "X-Gitlab-Event"
+	gitLabTokenHeader     = "X-Gitlab-Token"
+
+	gitHubHost    = "github.com"
+	gitHubAPIHost = "api.github.com"
+
+	// maxPayloadSize is the maximum size of the GitHub payloads.
+	maxPayloadSize = 25 << 20
+)
+
+var (
+	// ErrUnauthorized is returned when the signature or the token of the
+	// webhook doesn't match the secret.
+	ErrUnauthorized = errors.New("invalid webhook signature")
+	// ErrIgnored is returned for the events other than a new release or
+	// tag, e.g ping.
+	ErrIgnored = errors.New("event ignored")
+)
+
+// Event is a release or a tag created in a repository.
+type Event struct {
+	Provider pullrequest.Provider
+	Host     string
+	// Repository is the path of the repository, e.g owner/repo or
+	// group/subgroup/project.
+	Repository string
+	Tag        string
+}
+
+// Parse validates the webhook with the secret and returns its event, the
+// GitHub webhooks are signed with HMAC-SHA256 while the GitLab webhooks
+// carry the secret token.
+func Parse(header http.Header, body, secret []byte) (*Event, error) {
+	switch {
+	case header.Get(gitHubEventHeader) != "":
+		if !validGitHubSignature(header.Get(gitHubSignatureHeader), body, secret) {
+			return nil, ErrUnauthorized
+		}
+		return parseGitHub(header.Get(gitHubEventHeader), body)
+	case header.Get(gitLabEventHeader) != "":
+		token := header.Get(gitLabTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), secret) != 1 {
+			return nil, ErrUnauthorized
+		}
+		return parseGitLab(header.Get(gitLabEventHeader), body)
+	}
+	return nil, fmt.Errorf("webhook provider not recognized")
+}
+
+func validGitHubSignature(signature string, body, secret []byte) bool {
+	if !strings.HasPrefix(signature, gitHubSignaturePrefix) {
+		return false
+	}
+	sum, err := hex.DecodeString(strings.TrimPrefix(signature, gitHubSignaturePrefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sum, mac.Sum(nil))
+}
+
+type gitHubPayload struct {
+	Action  string `json:"action"`
+	Ref     string `json:"ref"`
+	RefType string `json:"ref_type"`
+	Release struct {
+		TagName string `json:"tag_name"`
+	} `json:"release"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repository"`
+}
+
+func parseGitHub(event string, body []byte) (*Event, error) {
+	var payload gitHubPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	var tag string
+	switch {
+	case event == "release" && (payload.Action == "published" || payload.Action == "released" || payload.Action == "created"):
+		tag = payload.Release.TagName
+	case event == "create" && payload.RefType == "tag":
+		tag = payload.Ref
+	default:
+		return nil, ErrIgnored
+	}
+
+	return newEvent(pullrequest.ProviderGitHub, payload.Repository.HTMLURL, payload.Repository.FullName, tag)
+}
+
+type gitLabPayload struct {
+	ObjectKind string `json:"object_kind"`
+	Action     string `json:"action"`
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Tag        string `json:"tag"`
+	Project    struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+	} `json:"project"`
+}
+
+func parseGitLab(event string, body []byte) (*Event, error) {
+	var payload gitLabPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	var tag string
+	switch {
+	case event == "Release Hook" && (payload.Action == "create" || payload.Action == "update"):
+		tag = payload.Tag
+	// The deleted tags are pushed with an empty commit.
+	case event == "Tag Push Hook" && strings.Trim(payload.After, "0") != "":
+		tag = strings.TrimPrefix(payload.Ref, "refs/tags/")
+	default:
+		return nil, ErrIgnored
+	}
+
+	return newEvent(pullrequest.ProviderGitLab, payload.Project.WebURL, payload.Project.PathWithNamespace, tag)
+}
+
+func newEvent(provider pullrequest.Provider, webURL, repository, tag string) (*Event, error) {
+	u, err := url.Parse(webURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Hostname() == "" || repository == "" {
+		return nil, fmt.Errorf("repository missing in the %s webhook", provider)
+	}
+	return &Event{Provider: provider, Host: u.Hostname(), Repository: repository, Tag: tag}, nil
+}
+
+// Matches returns true if the source finds its versions in the repository
+// of the event, the GitHub and git sources are matched.
+func (e *Event) Matches(source core.Source) bool {
+	switch {
+	case source.GitHub != nil:
+		host := gitHubHost
+		if source.GitHub.BaseURL != "" {
+			u, err := url.Parse(source.GitHub.BaseURL)
+			if err != nil {
+				return false
+			}
+			host = u.Hostname()
+		}
+		if host == gitHubAPIHost {
+			host = gitHubHost
+		}
+		return strings.EqualFold(e.Host, host) &&
+			strings.EqualFold(e.Repository, source.GitHub.Owner+"/"+source.GitHub.Repository)
+	case source.Git != nil:
+		remote, err := util.ParseGitRemoteURL(source.Git.URL)
+		if err != nil {
+			return false
+		}
+		return strings.EqualFold(e.Host, remote.Host) && strings.EqualFold(e.Repository, remote.Path)
+	}
+	return false
+}
+
+// NewHandler returns the handler of the webhooks, trigger is called with
+// the events and returns the names of the images updated.
+func NewHandler(secret []byte, trigger func(*Event) []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		event, err := Parse(r.Header, body, secret)
+		switch {
+		case errors.Is(err, ErrUnauthorized):
+			log.Warn().Str(core.RemoteKey, r.RemoteAddr).Msg(err.Error())
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		case errors.Is(err, ErrIgnored):
+			fmt.Fprintln(w, err.Error())
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		images := trigger(event)
+		log.Info().Str(core.RepoKey, event.Host+"/"+event.Repository).Str(core.TagKey, event.Tag).
+			Strs(core.ImageKey, images).Msg("webhook received")
+		if len(images) == 0 {
+			fmt.Fprintln(w, "no image found")
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, strings.Join(images, "\n"))
+	})
+}
@@ -0,0 +1,144 @@
+package webhook_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/spiarh/gojo/pkg/core"
+	"github.com/spiarh/gojo/pkg/pullrequest"
+	"github.com/spiarh/gojo/pkg/webhook"
+)
+
+var _ = Describe("webhook", func() {
+	secret := []byte("secret")
+
+	gitHubHeader := func(event, body string, key []byte) http.Header {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(body))
+		header := http.Header{}
+		header.Set("X-GitHub-Event", event)
+		header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		return header
+	}
+	gitLabHeader := func(event, token string) http.Header {
+		header := http.Header{}
+		header.Set("X-Gitlab-Event", event)
+		header.Set("X-Gitlab-Token", token)
+		return header
+	}
+
+	It("should parse the GitHub releases and tags", func() {
+		body := `{"action":"published","release":{"tag_name":"v2.3.5"},` +
+			`"repository":{"full_name":"haproxy/haproxy","html_url":"https://github.com/haproxy/haproxy"}}`
+		event, err := webhook.Parse(gitHubHeader("release", body, secret), []byte(body), secret)
+		Expect(err).To(BeNil())
+		Expect(*event).To(Equal(webhook.Event{
+			Provider: pullrequest.ProviderGitHub, Host: "github.com", Repository: "haproxy/haproxy", Tag: "v2.3.5",
+		}))
+
+		body = `{"ref":"v2.3.6","ref_type":"tag","repository":{"full_name":"haproxy/haproxy","html_url":"https://github.com/haproxy/haproxy"}}`
+		event, err = webhook.Parse(gitHubHeader("create", body, secret), []byte(body), secret)
+		Expect(err).To(BeNil())
+		Expect(event.Tag).To(Equal("v2.3.6"))
+
+		body = `{"ref":"main","ref_type":"branch","repository":{"full_name":"haproxy/haproxy","html_url":"https://github.com/haproxy/haproxy"}}`
+		_, err = webhook.Parse(gitHubHeader("create", body, secret), []byte(body), secret)
+		Expect(err).To(Equal(webhook.ErrIgnored))
+
+		_, err = webhook.Parse(gitHubHeader("ping", "{}", secret), []byte("{}"), secret)
+		Expect(err).To(Equal(webhook.ErrIgnored))
+	})
+
+	It("should reject the invalid GitHub signatures", func() {
+		body := `{"action":"published"}`
+		_, err := webhook.Parse(gitHubHeader("release", body, []byte("other")), []byte(body), secret)
+		Expect(err).To(Equal(webhook.ErrUnauthorized))
+
+		header := gitHubHeader("release", body, secret)
+		header.Del("X-Hub-Signature-256")
+		_, err = webhook.Parse(header, []byte(body), secret)
+		Expect(err).To(Equal(webhook.ErrUnauthorized))
+	})
+
+	It("should parse the GitLab releases and tags", func() {
+		body := `{"object_kind":"release","action":"create","tag":"v1.0.0",` +
+			`"project":{"path_with_namespace":"group/sub/project","web_url":"https://gitlab.example.com/group/sub/project"}}`
+		event, err := webhook.Parse(gitLabHeader("Release Hook", "secret"), []byte(body), secret)
+		Expect(err).To(BeNil())
+		Expect(*event).To(Equal(webhook.Event{
+			Provider: pullrequest.ProviderGitLab, Host: "gitlab.example.com", Repository: "group/sub/project", Tag: "v1.0.0",
+		}))
+
+		body = `{"object_kind":"tag_push","ref":"refs/tags/v1.0.1","after":"82b3d5ae55f7080f1e6022629cdb57bfae7cccc7",` +
+			`"project":{"path_with_namespace":"group/project","web_url":"https://gitlab.com/group/project"}}`
+		event, err = webhook.Parse(gitLabHeader("Tag Push Hook", "secret"), []byte(body), secret)
+		Expect(err).To(BeNil())
+		Expect(event.Tag).To(Equal("v1.0.1"))
+
+		body = `{"object_kind":"tag_push","ref":"refs/tags/v1.0.1","after":"0000000000000000000000000000000000000000",` +
+			`"project":{"path_with_namespace":"group/project","web_url":"https://gitlab.com/group/project"}}`
+		_, err = webhook.Parse(gitLabHeader("Tag Push Hook", "secret"), []byte(body), secret)
+		Expect(err).To(Equal(webhook.ErrIgnored))
+
+		_, err = webhook.Parse(gitLabHeader("Release Hook", "other"), []byte(body), secret)
+		Expect(err).To(Equal(webhook.ErrUnauthorized))
+	})
+
+	It("should match the sources of the repository", func() {
+		event := &webhook.Event{Host: "github.com", Repository: "HAProxy/haproxy"}
+		Expect(event.Matches(core.Source{Provider: core.Provider{
+			GitHub: &core.GitHubSource{Owner: "haproxy", Repository: "haproxy"},
+		}})).To(BeTrue())
+		Expect(event.Matches(core.Source{Provider: core.Provider{
+			GitHub: &core.GitHubSource{Owner: "haproxy", Repository: "haproxy", BaseURL: "https://api.github.com/"},
+		}})).To(BeTrue())
+		Expect(event.Matches(core.Source{Provider: core.Provider{
+			GitHub: &core.GitHubSource{Owner: "haproxy", Repository: "haproxy", BaseURL: "https://github.example.com/api/v3/"},
+		}})).To(BeFalse())
+		Expect(event.Matches(core.Source{Provider: core.Provider{
+			Git: &core.GitSource{URL: "https://github.com/haproxy/haproxy.git"},
+		}})).To(BeTrue())
+		Expect(event.Matches(core.Source{Provider: core.Provider{
+			Git: &core.GitSource{URL: "git@github.com:haproxy/dataplaneapi.git"},
+		}})).To(BeFalse())
+		Expect(event.Matches(core.Source{Provider: core.Provider{
+			PyPI: &core.PyPISource{Project: "haproxy"},
+		}})).To(BeFalse())
+	})
+
+	It("should trigger the images of the events", func() {
+		var events []*webhook.Event
+		handler := webhook.NewHandler(secret, func(event *webhook.Event) []string {
+			events = append(events, event)
+			return []string{"haproxy"}
+		})
+
+		body := `{"ref":"v2.3.6","ref_type":"tag","repository":{"full_name":"haproxy/haproxy","html_url":"https://github.com/haproxy/haproxy"}}`
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header = gitHubHeader("create", body, secret)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(rec.Body.String()).To(Equal("haproxy\n"))
+		Expect(events).To(HaveLen(1))
+
+		req = httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header = gitHubHeader("create", body, []byte("other"))
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+
+		req = httptest.NewRequest(http.MethodGet, "/webhook", nil)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(events).To(HaveLen(1))
+	})
+})